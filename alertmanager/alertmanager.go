@@ -0,0 +1,314 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package alertmanager turns high-order peaks into alerts, rather than
+// alerting on a static threshold. A rule evaluates a query over a window
+// of time, and an alert fires for each of the resulting series that has a
+// peak at the level of the rule, or above it, within the window. The alert
+// fires from the time of the peak, until the series drops back to where the
+// peak rose from. The alerts are posted to the Alertmanager v2 API, i.e.,
+// /api/v2/alerts.
+//
+// The latest sample of a window that is still rising is always a peak, as
+// far as the window goes, therefore, a peak at the trailing edge of the
+// window only fires once a later, lower sample confirms it.
+//
+// Every evaluation posts all the alerts that are firing, as Alertmanager
+// expects them to be re-sent, and posts the alerts that were firing, but
+// no longer are, as resolved.
+package alertmanager
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andr31g/peak-detector/peakdetect"
+	"github.com/andr31g/peak-detector/prometheus"
+)
+
+// Source evaluates a query over a range, and returns the resulting series.
+// It is implemented by *prometheus.Client.
+type Source interface {
+	QueryRange(ctx context.Context, query string, r prometheus.Range) ([]prometheus.Series, error)
+}
+
+// Rule describes when an alert fires, and what it carries. Besides the
+// labels of the series, and those of the rule, each alert is labelled with
+// the name of the rule, as "alertname". Besides the annotations of the rule,
+// each alert is annotated with the "level", the "value", and the "timestamp"
+// of the latest peak, where the level is the highest level the peak is at.
+type Rule struct {
+	Name string
+	// Query is evaluated over the Window up to the time of the evaluation,
+	// at a resolution of Step.
+	Query  string
+	Window time.Duration
+	Step   time.Duration
+	// Level is the lowest level at which a peak fires an alert,
+	// where 1 is the primary peaks.
+	Level       int
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// Alert is an alert, as posted to the Alertmanager v2 API.
+type Alert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations,omitempty"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+// The times are left out if they are not set, rather than be set to the
+// zero time, such that Alertmanager applies its defaults instead.
+func (a Alert) MarshalJSON() ([]byte, error) {
+	type alert Alert
+	return json.Marshal(struct {
+		alert
+		StartsAt *time.Time `json:"startsAt,omitempty"`
+		EndsAt   *time.Time `json:"endsAt,omitempty"`
+	}{alert(a), optionalTime(a.StartsAt), optionalTime(a.EndsAt)})
+}
+
+func optionalTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+type Notifier struct {
+	endpoint string
+	client   *http.Client
+	source   Source
+	rule     Rule
+	// The alerts that were firing as of the last evaluation, by their labels.
+	firing map[string]Alert
+}
+
+// CreateNotifier creates a notifier that posts the alerts of the rule to the
+// Alertmanager at the specified address, e.g., http://localhost:9093. If the
+// HTTP client is nil, then http.DefaultClient is used.
+func CreateNotifier(address string, client *http.Client, source Source, rule Rule) (*Notifier, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("alertmanager: unsupported address %q", address)
+	}
+	if rule.Name == "" {
+		return nil, fmt.Errorf("alertmanager: the rule has no name")
+	}
+	if rule.Level < 1 {
+		return nil, fmt.Errorf("alertmanager: level %d: levels are numbered from 1", rule.Level)
+	}
+	if rule.Window <= 0 || rule.Step <= 0 {
+		return nil, fmt.Errorf("alertmanager: the window, and the step, must be positive")
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Notifier{
+		endpoint: strings.TrimSuffix(u.String(), "/") + "/api/v2/alerts",
+		client:   client,
+		source:   source,
+		rule:     rule,
+		firing:   make(map[string]Alert),
+	}, nil
+}
+
+// Evaluate evaluates the rule as of the specified time, and posts the alerts
+// that are firing, along with those that were, but no longer are, as resolved.
+// If the alerts cannot be posted, then they are posted again upon the next
+// evaluation, since the alerts that are firing are always posted, and the
+// resolved ones are only forgotten once they were posted.
+func (n *Notifier) Evaluate(ctx context.Context, now time.Time) error {
+	r := prometheus.Range{Start: now.Add(-n.rule.Window), End: now, Step: n.rule.Step}
+	series, err := n.source.QueryRange(ctx, n.rule.Query, r)
+	if err != nil {
+		return err
+	}
+
+	firing := make(map[string]Alert)
+	for _, s := range series {
+		p, ok, err := firingPeak(n.rule.Level, s.Samples)
+		if err != nil {
+			return fmt.Errorf("alertmanager: series %v: %w", s.Labels, err)
+		}
+		if !ok {
+			continue
+		}
+		alert := n.alert(s.Labels, p)
+		key := labelsKey(alert.Labels)
+		alert.StartsAt = p.timestamp
+		if previous, ok := n.firing[key]; ok {
+			alert.StartsAt = previous.StartsAt
+		}
+		firing[key] = alert
+	}
+
+	alerts := make([]Alert, 0, len(firing)+len(n.firing))
+	for _, alert := range firing {
+		alerts = append(alerts, alert)
+	}
+	for key, alert := range n.firing {
+		if _, ok := firing[key]; !ok {
+			alert.EndsAt = now
+			alerts = append(alerts, alert)
+		}
+	}
+	if len(alerts) == 0 {
+		n.firing = firing
+		return nil
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		return labelsKey(alerts[i].Labels) < labelsKey(alerts[j].Labels)
+	})
+	if err := n.post(ctx, alerts); err != nil {
+		return err
+	}
+	n.firing = firing
+	return nil
+}
+
+// Run evaluates the rule at the specified interval, until the context is
+// done. The errors of each evaluation are reported to the function, if any.
+func (n *Notifier) Run(ctx context.Context, interval time.Duration, report func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := n.Evaluate(ctx, time.Now()); err != nil && report != nil && ctx.Err() == nil {
+			report(err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Describes the peak that fires the alert.
+func (n *Notifier) alert(seriesLabels map[string]string, p peak) Alert {
+	labels := make(map[string]string, len(seriesLabels)+len(n.rule.Labels)+1)
+	for name, value := range seriesLabels {
+		if name != "__name__" {
+			labels[name] = value
+		}
+	}
+	for name, value := range n.rule.Labels {
+		labels[name] = value
+	}
+	labels["alertname"] = n.rule.Name
+
+	annotations := make(map[string]string, len(n.rule.Annotations)+3)
+	for name, value := range n.rule.Annotations {
+		annotations[name] = value
+	}
+	annotations["level"] = strconv.Itoa(p.level)
+	annotations["value"] = strconv.FormatFloat(p.value, 'g', -1, 64)
+	annotations["timestamp"] = p.timestamp.UTC().Format(time.RFC3339)
+	return Alert{Labels: labels, Annotations: annotations}
+}
+
+// The peak that fires an alert, along with the highest level it is at.
+type peak struct {
+	level     int
+	timestamp time.Time
+	value     float64
+}
+
+// Finds the latest of the peaks at the level, or above it, which is confirmed
+// by a later, lower sample, unless the series has since dropped back to where
+// the peak rose from, i.e., unless the latest sample is no higher than the
+// peak, less its prominence. The NaN samples of the series are skipped.
+func firingPeak(level int, s peakdetect.Series[float64]) (peak, bool, error) {
+	levels, _, err := peakdetect.IteratePeakDetectSeriesLevelsWithNaNPolicy[float64](math.MaxUint, s, peakdetect.NaNSkip)
+	if err != nil || len(levels) < level {
+		return peak{}, false, err
+	}
+	edge, latest := trailingEdge(s.Values)
+	peaks := levels[level-1]
+	i := len(peaks.Indices) - 1
+	for i >= 0 && peaks.Indices[i] >= edge {
+		i--
+	}
+	if i < 0 || latest <= peaks.Values[i]-peaks.Prominences[i] {
+		return peak{}, false, nil
+	}
+	p := peak{level, peaks.Timestamps[i], peaks.Values[i]}
+	// The peaks at each level are among those at the level below it.
+	for _, above := range levels[level:] {
+		if _, found := slices.BinarySearch(above.Indices, peaks.Indices[i]); !found {
+			break
+		}
+		p.level = above.Level
+	}
+	return p, true, nil
+}
+
+// Returns the index of the first sample of the plateau at the trailing edge
+// of the samples, i.e., of the samples that no later, lower sample follows,
+// along with their value. The NaN samples are skipped.
+func trailingEdge(values []float64) (int, float64) {
+	edge, latest := len(values), math.NaN()
+	for i := len(values) - 1; i >= 0; i-- {
+		if math.IsNaN(values[i]) {
+			continue
+		}
+		if !math.IsNaN(latest) && values[i] != latest {
+			break
+		}
+		edge, latest = i, values[i]
+	}
+	return edge, latest
+}
+
+func (n *Notifier) post(ctx context.Context, alerts []Alert) error {
+	body, err := json.Marshal(alerts)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, n.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	response, err := n.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf("alertmanager: %s: %s", response.Status, bytes.TrimSpace(message))
+	}
+	return nil
+}
+
+func labelsKey(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s=%q,", name, labels[name])
+	}
+	return b.String()
+}
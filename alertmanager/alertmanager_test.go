@@ -0,0 +1,312 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package alertmanager
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andr31g/peak-detector/peakdetect"
+	"github.com/andr31g/peak-detector/prometheus"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Serves the series of each instance, one sample per minute from the start.
+type source map[string][]float64
+
+func (s source) QueryRange(ctx context.Context, query string, r prometheus.Range) ([]prometheus.Series, error) {
+	series := make([]prometheus.Series, 0, len(s))
+	for _, instance := range []string{"a", "b"} {
+		values, ok := s[instance]
+		if !ok {
+			continue
+		}
+		timestamps := make([]time.Time, len(values))
+		for i := range timestamps {
+			timestamps[i] = start.Add(time.Duration(i) * time.Minute)
+		}
+		series = append(series, prometheus.Series{
+			Labels:  map[string]string{"__name__": "errors", "instance": instance},
+			Samples: peakdetect.CreateSeries[float64](timestamps, values),
+		})
+	}
+	return series, nil
+}
+
+// A stand-in for Alertmanager, which records the alerts posted to it.
+type alertmanager struct {
+	*httptest.Server
+	posts [][]map[string]any
+}
+
+func createAlertmanager(t *testing.T) *alertmanager {
+	a := &alertmanager{}
+	a.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v2/alerts" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var alerts []map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&alerts); err != nil {
+			t.Error(err)
+		}
+		a.posts = append(a.posts, alerts)
+	}))
+	t.Cleanup(a.Close)
+	return a
+}
+
+func TestEvaluate(t *testing.T) {
+	am := createAlertmanager(t)
+	s := source{
+		// The secondary peak of 9 is at 3, and the primary peak of 4 at 5,
+		// and the series has not yet dropped back to 1, from which 9 rose.
+		"a": {1, 3, 2, 9, 1, 4, 2},
+		// There are no secondary peaks.
+		"b": {1, 2, 1},
+	}
+	rule := Rule{
+		Name:        "ErrorSpike",
+		Query:       "errors",
+		Window:      time.Hour,
+		Step:        time.Minute,
+		Level:       2,
+		Labels:      map[string]string{"severity": "page"},
+		Annotations: map[string]string{"summary": "errors spiked"},
+	}
+	n, err := CreateNotifier(am.URL, am.Client(), s, rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	labels := map[string]any{"alertname": "ErrorSpike", "instance": "a", "severity": "page"}
+	firing := map[string]any{
+		"labels": labels,
+		"annotations": map[string]any{
+			"summary":   "errors spiked",
+			"level":     "2",
+			"value":     "9",
+			"timestamp": "2024-01-01T00:03:00Z",
+		},
+		// The time of the peak, rather than that of the evaluation.
+		"startsAt": "2024-01-01T00:03:00Z",
+	}
+
+	now := start.Add(time.Hour)
+	if err := n.Evaluate(context.Background(), now); err != nil {
+		t.Fatal(err)
+	}
+	if expected := [][]map[string]any{{firing}}; !reflect.DeepEqual(expected, am.posts) {
+		t.Fatalf("expected %v, got %v", expected, am.posts)
+	}
+
+	// The alert is sent again, with the time at which it started firing.
+	if err := n.Evaluate(context.Background(), now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if len(am.posts) != 2 || !reflect.DeepEqual(am.posts[1], []map[string]any{firing}) {
+		t.Fatalf("expected the alert to be sent again, got %v", am.posts)
+	}
+
+	// Once the series drops back, the alert is resolved, even though the
+	// peak is still within the window, and then forgotten.
+	s["a"] = []float64{1, 3, 2, 9, 1, 4, 1}
+	if err := n.Evaluate(context.Background(), now.Add(2*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	resolved := map[string]any{
+		"labels":      labels,
+		"annotations": firing["annotations"],
+		"startsAt":    "2024-01-01T00:03:00Z",
+		"endsAt":      "2024-01-01T01:02:00Z",
+	}
+	if len(am.posts) != 3 || !reflect.DeepEqual(am.posts[2], []map[string]any{resolved}) {
+		t.Fatalf("expected the alert to be resolved, got %v", am.posts)
+	}
+	if err := n.Evaluate(context.Background(), now.Add(3*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if len(am.posts) != 3 {
+		t.Fatalf("expected nothing to be sent, got %v", am.posts[3:])
+	}
+}
+
+func TestEvaluatePrimary(t *testing.T) {
+	am := createAlertmanager(t)
+	rule := Rule{Name: "Spike", Query: "errors", Window: time.Hour, Step: time.Minute, Level: 1}
+	n, err := CreateNotifier(am.URL, am.Client(), source{"b": {1, 3, 2}}, rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Evaluate(context.Background(), start.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if len(am.posts) != 1 || len(am.posts[0]) != 1 || am.posts[0][0]["annotations"].(map[string]any)["value"] != "3" {
+		t.Errorf("expected the primary peak of 3 to fire, got %v", am.posts)
+	}
+}
+
+// The alert fires for the latest confirmed peak at the level of the rule,
+// or above it, and is annotated with the highest level the peak is at.
+func TestEvaluateLevel(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		// The annotations of the alert, if it fires.
+		level, value, timestamp string
+	}{
+		// The peak of 9 is a secondary peak.
+		{"higher level", []float64{1, 3, 2, 9, 5}, "2", "9", "2024-01-01T00:03:00Z"},
+		{"latest peak", []float64{1, 9, 2, 4, 3}, "1", "4", "2024-01-01T00:03:00Z"},
+		// The latest sample of a rising window is a peak, unless a later,
+		// lower sample is yet to confirm it.
+		{"rising", []float64{1, 2, 3}, "", "", ""},
+		{"rising plateau", []float64{1, 3, 3}, "", "", ""},
+		{"confirmed", []float64{1, 2, 3, 2}, "1", "3", "2024-01-01T00:02:00Z"},
+		{"before the edge", []float64{1, 5, 2, 3}, "2", "5", "2024-01-01T00:01:00Z"},
+		{"before a NaN edge", []float64{1, 2, 3, math.NaN()}, "", "", ""},
+		// The series dropped back to where the peak rose from.
+		{"dropped back", []float64{2, 9, 5, 2}, "", "", ""},
+		{"dropped below", []float64{2, 9, 5, 1}, "", "", ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			am := createAlertmanager(t)
+			rule := Rule{Name: "Spike", Query: "errors", Window: time.Hour, Step: time.Minute, Level: 1}
+			n, err := CreateNotifier(am.URL, am.Client(), source{"a": test.values}, rule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := n.Evaluate(context.Background(), start.Add(time.Hour)); err != nil {
+				t.Fatal(err)
+			}
+			if test.level == "" {
+				if len(am.posts) != 0 {
+					t.Errorf("expected no alert, got %v", am.posts)
+				}
+				return
+			}
+			if len(am.posts) != 1 || len(am.posts[0]) != 1 {
+				t.Fatalf("expected an alert, got %v", am.posts)
+			}
+			alert := am.posts[0][0]
+			expected := map[string]any{"level": test.level, "value": test.value, "timestamp": test.timestamp}
+			if !reflect.DeepEqual(expected, alert["annotations"]) || alert["startsAt"] != test.timestamp {
+				t.Errorf("expected %v, from %s, got %v", expected, test.timestamp, alert)
+			}
+		})
+	}
+}
+
+// An alert resolves once the series drops back, and fires again, from the
+// time of the next peak, once the series rises again.
+func TestEvaluateResolution(t *testing.T) {
+	am := createAlertmanager(t)
+	s := source{"a": {1, 5, 3}}
+	rule := Rule{Name: "Spike", Query: "errors", Window: time.Hour, Step: time.Minute, Level: 1}
+	n, err := CreateNotifier(am.URL, am.Client(), s, rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := start.Add(time.Hour)
+	evaluate := func(values ...float64) map[string]any {
+		t.Helper()
+		s["a"] = values
+		now = now.Add(time.Minute)
+		posts := len(am.posts)
+		if err := n.Evaluate(context.Background(), now); err != nil {
+			t.Fatal(err)
+		}
+		if len(am.posts) == posts {
+			return nil
+		}
+		if len(am.posts[posts]) != 1 {
+			t.Fatalf("expected a single alert, got %v", am.posts[posts])
+		}
+		return am.posts[posts][0]
+	}
+
+	if alert := evaluate(1, 5, 3); alert == nil || alert["startsAt"] != "2024-01-01T00:01:00Z" || alert["endsAt"] != nil {
+		t.Fatalf("expected the alert to fire from the peak, got %v", alert)
+	}
+	// The series has not yet dropped back to 1, from which the peak rose.
+	if alert := evaluate(1, 5, 3, 2); alert == nil || alert["endsAt"] != nil {
+		t.Fatalf("expected the alert to keep firing, got %v", alert)
+	}
+	if alert := evaluate(1, 5, 3, 2, 1); alert == nil || alert["endsAt"] != "2024-01-01T01:03:00Z" {
+		t.Fatalf("expected the alert to be resolved, got %v", alert)
+	}
+	if alert := evaluate(1, 5, 3, 2, 1, 1); alert != nil {
+		t.Fatalf("expected the alert to be forgotten, got %v", alert)
+	}
+	// The series rises again, and the new peak is confirmed.
+	if alert := evaluate(1, 5, 3, 2, 1, 1, 4, 3); alert == nil || alert["startsAt"] != "2024-01-01T00:06:00Z" || alert["endsAt"] != nil {
+		t.Fatalf("expected the alert to fire from the new peak, got %v", alert)
+	}
+}
+
+// The NaN samples, as Prometheus reports them, e.g., of a division by zero,
+// are skipped, and the peak is at the index among all the samples.
+func TestEvaluateNaN(t *testing.T) {
+	am := createAlertmanager(t)
+	rule := Rule{Name: "Spike", Query: "errors", Window: time.Hour, Step: time.Minute, Level: 2}
+	n, err := CreateNotifier(am.URL, am.Client(), source{"a": {1, 3, math.NaN(), 9, 1, 4, 2}}, rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Evaluate(context.Background(), start.Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if len(am.posts) != 1 || len(am.posts[0]) != 1 ||
+		am.posts[0][0]["annotations"].(map[string]any)["timestamp"] != "2024-01-01T00:03:00Z" {
+		t.Errorf("expected the secondary peak of 9 at 3 to fire, got %v", am.posts)
+	}
+}
+
+func TestEvaluateUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	rule := Rule{Name: "Spike", Query: "errors", Window: time.Hour, Step: time.Minute, Level: 1}
+	n, err := CreateNotifier(server.URL, server.Client(), source{"b": {1, 3, 2}}, rule)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = n.Evaluate(context.Background(), start.Add(time.Hour))
+	if err == nil || !strings.Contains(err.Error(), "unavailable") {
+		t.Errorf("expected the alertmanager to be unavailable, got %v", err)
+	}
+}
+
+func TestCreateNotifier(t *testing.T) {
+	valid := Rule{Name: "Spike", Query: "errors", Window: time.Hour, Step: time.Minute, Level: 1}
+	tests := []struct {
+		name    string
+		address string
+		rule    func(r *Rule)
+	}{
+		{"address", "localhost:9093", func(r *Rule) {}},
+		{"name", "http://localhost:9093", func(r *Rule) { r.Name = "" }},
+		{"level", "http://localhost:9093", func(r *Rule) { r.Level = 0 }},
+		{"step", "http://localhost:9093", func(r *Rule) { r.Step = 0 }},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rule := valid
+			test.rule(&rule)
+			if _, err := CreateNotifier(test.address, nil, source{}, rule); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
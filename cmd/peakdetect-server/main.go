@@ -0,0 +1,59 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Command peakdetect-server serves the peak detector over HTTP, see the
+// server package for the API. It shuts down gracefully upon an interrupt,
+// or upon being terminated.
+//
+// If the address of a Prometheus server is given, then the peaks in the
+// series it returns are also served under /grafana/, as a Grafana
+// "Simple JSON" datasource, see the grafana package.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/andr31g/peak-detector/grafana"
+	"github.com/andr31g/peak-detector/prometheus"
+	"github.com/andr31g/peak-detector/server"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "`address` to listen on")
+	maxBytes := flag.Int64("max-bytes", server.DefaultMaxBytes, "maximum size of a request body, in `bytes`")
+	grace := flag.Duration("grace", 10*time.Second, "how long to wait for the requests in progress to complete upon shutdown")
+	prometheusAddr := flag.String("prometheus", "", "`address` of the Prometheus server to serve the Grafana datasource from, e.g., http://localhost:9090")
+	flag.Parse()
+
+	mux := http.NewServeMux()
+	mux.Handle("/", server.CreateServer(*maxBytes))
+	if *prometheusAddr != "" {
+		client, err := prometheus.CreateClient(*prometheusAddr, nil)
+		if err != nil {
+			log.Fatal(err)
+		}
+		mux.Handle("/grafana/", http.StripPrefix("/grafana", grafana.CreateHandler(client)))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	l, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("listening on %s", l.Addr())
+	if err := server.Serve(ctx, l, mux, *grace); err != nil {
+		log.Fatal(err)
+	}
+	log.Print("shut down")
+}
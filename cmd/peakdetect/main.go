@@ -0,0 +1,291 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Command peakdetect detects the peaks in a series of numbers, read from
+// a file, or from the standard input, and prints the index and the value
+// of each peak, at each level of the peak hierarchy.
+//
+// Usage:
+//
+//	peakdetect [flags] [file]
+//
+// The numbers are separated by newlines, or by commas, and there is no limit
+// on the length of a line. If a column is given, then the input is read as
+// CSV, and the numbers are taken from that column. A NaN cannot be ordered,
+// and is reported along with the line, or the record, and the column, at
+// which it was read.
+//
+// The exit status is 0 if there are peaks at the last level, 1 if there are
+// none, and 2 if the input, or the flags, could not be made sense of. When
+// the detection runs to completion, the last level is the last one that
+// has any peaks.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/andr31g/peak-detector/peakdetect"
+)
+
+const (
+	exitPeaks   = 0
+	exitNoPeaks = 1
+	exitError   = 2
+)
+
+// Level is the peaks at one level of the hierarchy. The indices are
+// those of the numbers read, irrespective of the level.
+type Level struct {
+	Level int    `json:"level"`
+	Peaks []Peak `json:"peaks"`
+}
+
+type Peak struct {
+	Index int     `json:"index"`
+	Value float64 `json:"value"`
+}
+
+type options struct {
+	levels   uint
+	complete bool
+	column   string
+	header   bool
+	format   string
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var o options
+	flags := flag.NewFlagSet("peakdetect", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.UintVar(&o.levels, "levels", 1, "number of `levels` to detect, where 1 is the primary peaks only")
+	flags.BoolVar(&o.complete, "complete", false, "detect all the levels, until there are no more peaks")
+	flags.StringVar(&o.column, "column", "", "read the input as CSV, and take the numbers from this `column`, either its name, or its 1-based index")
+	flags.BoolVar(&o.header, "header", false, "skip the first CSV record, which is implied if the column is given by name")
+	flags.StringVar(&o.format, "format", "text", "output `format`, either text or json")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: peakdetect [flags] [file]\n")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitPeaks
+		}
+		return exitError
+	}
+	if err := o.validate(flags.NArg()); err != nil {
+		fmt.Fprintf(stderr, "peakdetect: %v\n", err)
+		return exitError
+	}
+
+	input := stdin
+	if flags.NArg() == 1 {
+		f, err := os.Open(flags.Arg(0))
+		if err != nil {
+			fmt.Fprintf(stderr, "peakdetect: %v\n", err)
+			return exitError
+		}
+		defer f.Close()
+		input = f
+	}
+
+	samples, err := o.read(input)
+	if err != nil {
+		fmt.Fprintf(stderr, "peakdetect: %v\n", err)
+		return exitError
+	}
+	levels, err := o.detect(samples)
+	if err != nil {
+		fmt.Fprintf(stderr, "peakdetect: %v\n", err)
+		return exitError
+	}
+	if err := o.write(stdout, levels); err != nil {
+		fmt.Fprintf(stderr, "peakdetect: %v\n", err)
+		return exitError
+	}
+
+	if len(levels) == 0 || len(levels[len(levels)-1].Peaks) == 0 {
+		return exitNoPeaks
+	}
+	return exitPeaks
+}
+
+func (o *options) validate(args int) error {
+	if args > 1 {
+		return errors.New("at most one file may be given")
+	}
+	if o.levels == 0 && !o.complete {
+		return errors.New("the number of levels must be at least 1")
+	}
+	if o.format != "text" && o.format != "json" {
+		return fmt.Errorf("unknown format %q", o.format)
+	}
+	return nil
+}
+
+func (o *options) read(r io.Reader) ([]float64, error) {
+	if o.column != "" {
+		return o.readColumn(r)
+	}
+	samples := make([]float64, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Split(splitFields)
+	for line, column := 1, 1; scanner.Scan(); {
+		token := scanner.Text()
+		if field := strings.TrimSpace(strings.TrimSuffix(token, ",")); field != "" {
+			sample, err := parseSample(field)
+			if err != nil {
+				return nil, fmt.Errorf("line %d, column %d: %w", line, column+strings.Index(token, field), err)
+			}
+			samples = append(samples, sample)
+		}
+		if strings.HasSuffix(token, "\n") {
+			line, column = line+1, 1
+		} else {
+			column += len(token)
+		}
+	}
+	return samples, scanner.Err()
+}
+
+// Splits the input into fields, each along with the comma, or the newline,
+// that ends it, if any, such that a line may be of any length.
+func splitFields(data []byte, atEOF bool) (int, []byte, error) {
+	if i := bytes.IndexAny(data, ",\n"); i >= 0 {
+		return i + 1, data[:i+1], nil
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// Parses the field as a sample, which must not be NaN, since it cannot be
+// ordered, whereas the infinities are ordered, and are taken as they are.
+func parseSample(field string) (float64, error) {
+	sample, err := strconv.ParseFloat(field, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(sample) {
+		return 0, fmt.Errorf("%q: %w", field, peakdetect.ErrNaNSample)
+	}
+	return sample, nil
+}
+
+func (o *options) readColumn(r io.Reader) ([]float64, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	column, err := strconv.Atoi(o.column)
+	byName := err != nil
+	if !byName && column < 1 {
+		return nil, fmt.Errorf("column %d: columns are numbered from 1", column)
+	}
+	column--
+
+	samples := make([]float64, 0)
+	for record := 1; ; record++ {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			return samples, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if record == 1 && byName {
+			if column = indexOf(fields, o.column); column < 0 {
+				return nil, fmt.Errorf("column %q not found in the header", o.column)
+			}
+			continue
+		} else if record == 1 && o.header {
+			continue
+		}
+		if column >= len(fields) {
+			return nil, fmt.Errorf("record %d: no column %d", record, column+1)
+		}
+		sample, err := parseSample(strings.TrimSpace(fields[column]))
+		if err != nil {
+			return nil, fmt.Errorf("record %d, column %d: %w", record, column+1, err)
+		}
+		samples = append(samples, sample)
+	}
+}
+
+func indexOf(fields []string, name string) int {
+	for i, field := range fields {
+		if strings.TrimSpace(field) == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Detects the primary peaks, and then the peaks at each level above them,
+// until either the number of levels is reached, or a level has no peaks.
+// When the detection runs to completion, the level without peaks is left
+// out, unless it is the primary level.
+func (o *options) detect(samples []float64) ([]Level, error) {
+	var pyramid peakdetect.PeakPyramid[float64]
+	var err error
+	if o.complete {
+		pyramid, err = peakdetect.DetectPeakPyramidToCompletionE[float64](samples)
+	} else {
+		pyramid, err = peakdetect.DetectPeakPyramidE[float64](o.levels, samples)
+	}
+	if err != nil {
+		return nil, err
+	}
+	levels := make([]Level, 0, pyramid.GetLevelCount()+1)
+	for l := 1; l <= pyramid.GetLevelCount(); l++ {
+		levels = append(levels, level(l, pyramid.GetPeaks(l), pyramid.GetValues(l)))
+	}
+	// The pyramid stops short of the levels only at a level without peaks,
+	// which is reported, unless the primary level already has none.
+	if !o.complete && uint(len(levels)) < o.levels && len(levels[len(levels)-1].Peaks) > 0 {
+		levels = append(levels, Level{len(levels) + 1, []Peak{}})
+	}
+	return levels, nil
+}
+
+func level(number int, indices []int, values []float64) Level {
+	peaks := make([]Peak, len(indices))
+	for i, at := range indices {
+		peaks[i] = Peak{at, values[i]}
+	}
+	return Level{number, peaks}
+}
+
+// The text format is a line per peak, made up of the level, the index,
+// and the value, separated by tabs, such that it can be fed to the likes
+// of cut, or awk.
+func (o *options) write(w io.Writer, levels []Level) error {
+	if o.format == "json" {
+		encoder := json.NewEncoder(w)
+		return encoder.Encode(struct {
+			Levels []Level `json:"levels"`
+		}{levels})
+	}
+	writer := bufio.NewWriter(w)
+	for _, l := range levels {
+		for _, p := range l.Peaks {
+			fmt.Fprintf(writer, "%d\t%d\t%s\n", l.Level, p.Index, strconv.FormatFloat(p.Value, 'g', -1, 64))
+		}
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/andr31g/peak-detector/peakdetect"
+)
+
+const multipass = "1\n8\n3\n5\n7\n2\n3\n6\n9\n0,1,3,12,1,4,8,2,4,1,9\n"
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		stdin  string
+		stdout string
+		status int
+	}{
+		{
+			name:   "primary",
+			stdin:  "1, 3, 2, 2, 5, 4",
+			stdout: "1\t1\t3\n1\t4\t5\n",
+			status: exitPeaks,
+		},
+		{
+			name:   "no peaks",
+			stdin:  "2\n2\n2\n",
+			stdout: "",
+			status: exitNoPeaks,
+		},
+		{
+			name:   "levels",
+			args:   []string{"-levels", "2"},
+			stdin:  multipass,
+			stdout: "1\t1\t8\n1\t4\t7\n1\t8\t9\n1\t12\t12\n1\t15\t8\n1\t17\t4\n1\t19\t9\n2\t1\t8\n2\t12\t12\n2\t19\t9\n",
+			status: exitPeaks,
+		},
+		{
+			name:   "levels beyond the last level with peaks",
+			args:   []string{"-levels", "5"},
+			stdin:  "1\n3\n2\n5\n1\n",
+			stdout: "1\t1\t3\n1\t3\t5\n2\t3\t5\n",
+			status: exitNoPeaks,
+		},
+		{
+			name:   "complete",
+			args:   []string{"-complete"},
+			stdin:  "1\n3\n2\n5\n1\n",
+			stdout: "1\t1\t3\n1\t3\t5\n2\t3\t5\n",
+			status: exitPeaks,
+		},
+		{
+			name:   "json",
+			args:   []string{"-format", "json", "-levels", "2"},
+			stdin:  "1,3,2,5,1.5",
+			stdout: `{"levels":[{"level":1,"peaks":[{"index":1,"value":3},{"index":3,"value":5}]},{"level":2,"peaks":[{"index":3,"value":5}]}]}` + "\n",
+			status: exitPeaks,
+		},
+		{
+			name:   "json without peaks",
+			args:   []string{"-format", "json"},
+			stdin:  "",
+			stdout: `{"levels":[{"level":1,"peaks":[]}]}` + "\n",
+			status: exitNoPeaks,
+		},
+		{
+			name:   "column by name",
+			args:   []string{"-column", "value"},
+			stdin:  "time,value\n0,1\n1,3\n2,2\n",
+			stdout: "1\t1\t3\n",
+			status: exitPeaks,
+		},
+		{
+			name:   "column by index",
+			args:   []string{"-column", "2", "-header"},
+			stdin:  "time,value\n0,1\n1,3\n2,2\n",
+			stdout: "1\t1\t3\n",
+			status: exitPeaks,
+		},
+		{name: "unknown column", args: []string{"-column", "other"}, stdin: "time,value\n0,1\n", status: exitError},
+		{name: "missing column", args: []string{"-column", "3"}, stdin: "0,1\n", status: exitError},
+		{name: "not a number", stdin: "1\nfoo\n", status: exitError},
+		{name: "NaN", stdin: "1\nNaN\n2\n", status: exitError},
+		{name: "unknown format", args: []string{"-format", "xml"}, status: exitError},
+		{name: "zero levels", args: []string{"-levels", "0"}, status: exitError},
+		{name: "unknown flag", args: []string{"-unknown"}, status: exitError},
+		{name: "missing file", args: []string{filepath.Join(t.TempDir(), "missing")}, status: exitError},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			status := run(test.args, strings.NewReader(test.stdin), &stdout, &stderr)
+			if status != test.status {
+				t.Errorf("expected status %d, got %d, %s", test.status, status, stderr.String())
+			}
+			if stdout.String() != test.stdout {
+				t.Errorf("expected output %q, got %q", test.stdout, stdout.String())
+			}
+			if (status == exitError) != (stderr.Len() > 0) {
+				t.Errorf("unexpected diagnostics %q for status %d", stderr.String(), status)
+			}
+		})
+	}
+}
+
+// The errors are reported along with where in the input they were found.
+func TestRunErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		stdin  string
+		stderr string
+	}{
+		{"NaN", nil, "1\nNaN\n2\n", "line 2, column 1: \"NaN\": " + peakdetect.ErrNaNSample.Error()},
+		{"NaN after a comma", nil, "1, 2\n3,  nan", "line 2, column 5: \"nan\": " + peakdetect.ErrNaNSample.Error()},
+		{"not a number", nil, "1,2,3\r\n4,foo\r\n", "line 2, column 3: strconv.ParseFloat"},
+		{"NaN in a column", []string{"-column", "value"}, "time,value\n0,1\n1,NaN\n", "record 3, column 2: \"NaN\""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if status := run(test.args, strings.NewReader(test.stdin), &stdout, &stderr); status != exitError {
+				t.Errorf("expected status %d, got %d", exitError, status)
+			}
+			if !strings.Contains(stderr.String(), test.stderr) {
+				t.Errorf("expected %q, got %q", test.stderr, stderr.String())
+			}
+		})
+	}
+}
+
+// A line may be far longer than bufio.Scanner takes a line to be at most.
+func TestRunLongLine(t *testing.T) {
+	fields := make([]string, 100_000)
+	for i := range fields {
+		fields[i] = strconv.Itoa(i % 3)
+	}
+	var stdout, stderr bytes.Buffer
+	status := run(nil, strings.NewReader(strings.Join(fields, ", ")+"\n"), &stdout, &stderr)
+	if status != exitPeaks {
+		t.Fatalf("expected status %d, got %d, %s", exitPeaks, status, stderr.String())
+	}
+	if lines := strings.Count(stdout.String(), "\n"); lines != len(fields)/3 {
+		t.Errorf("expected %d peaks, got %d", len(fields)/3, lines)
+	}
+}
+
+func TestRunFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "samples.txt")
+	if err := os.WriteFile(name, []byte(multipass), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var stdout, stderr bytes.Buffer
+	if status := run([]string{"-levels", "3", name}, strings.NewReader(""), &stdout, &stderr); status != exitPeaks {
+		t.Fatalf("expected status %d, got %d, %s", exitPeaks, status, stderr.String())
+	}
+	if !strings.HasSuffix(stdout.String(), "2\t19\t9\n3\t12\t12\n") {
+		t.Errorf("expected the tertiary peak at 12, got %q", stdout.String())
+	}
+}
@@ -0,0 +1,372 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package grafana serves the peaks detected in the series of a source, such
+// as Prometheus, as a Grafana "Simple JSON" datasource, i.e., it serves the
+// /, /search, /query and /annotations endpoints of that protocol.
+//
+// A target is a query of the source, optionally preceded by the level of the
+// peaks to detect in each of its series, e.g., "level=2 rate(errors[5m])".
+// For each of the series, the query responds with the series itself, along
+// with a series of the peaks, at the level, or at each of the levels, if no
+// level is given. The peak series are inflated, i.e., they have a value at
+// every timestamp of the series, which is 0, wherever there is no peak.
+//
+// The annotations are the peaks at the level, or at the last level that has
+// any peaks, if no level is given.
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andr31g/peak-detector/peakdetect"
+	"github.com/andr31g/peak-detector/prometheus"
+)
+
+// Source evaluates a query over a range, and returns the resulting series.
+// It is implemented by *prometheus.Client.
+type Source interface {
+	QueryRange(ctx context.Context, query string, r prometheus.Range) ([]prometheus.Series, error)
+}
+
+const (
+	// The number of levels suggested by the search, for each target.
+	searchLevels = 3
+	// The resolution of a query that does not specify one, and of the
+	// annotations, unless the range is too long for it, see minStep.
+	defaultStep = 15 * time.Second
+	// The most points Prometheus returns for each series of a range query.
+	maxPoints = 11_000
+	maxBytes  = 1 << 20
+)
+
+type Handler struct {
+	source Source
+	mux    *http.ServeMux
+}
+
+func CreateHandler(source Source) *Handler {
+	h := &Handler{source, http.NewServeMux()}
+	h.mux.HandleFunc("/", h.test)
+	h.mux.HandleFunc("/search", h.search)
+	h.mux.HandleFunc("/query", h.query)
+	h.mux.HandleFunc("/annotations", h.annotations)
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// The datasource is tested by checking that the root responds with 200 OK.
+func (h *Handler) test(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+type searchRequest struct {
+	Target string `json:"target"`
+}
+
+// Suggests the target itself, followed by the target at each of the first few levels.
+func (h *Handler) search(w http.ResponseWriter, r *http.Request) {
+	var request searchRequest
+	if !decode(w, r, &request) {
+		return
+	}
+	_, query, err := parseTarget(request.Target)
+	if err != nil || query == "" {
+		writeJSON(w, http.StatusOK, []string{})
+		return
+	}
+	targets := []string{query}
+	for level := 1; level <= searchLevels; level++ {
+		targets = append(targets, fmt.Sprintf("level=%d %s", level, query))
+	}
+	writeJSON(w, http.StatusOK, targets)
+}
+
+type timeRange struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+type queryRequest struct {
+	Range         timeRange `json:"range"`
+	IntervalMs    int64     `json:"intervalMs"`
+	MaxDataPoints int64     `json:"maxDataPoints"`
+	Targets       []struct {
+		Target string `json:"target"`
+		RefID  string `json:"refId"`
+	} `json:"targets"`
+}
+
+type timeSeries struct {
+	Target     string      `json:"target"`
+	Datapoints []datapoint `json:"datapoints"`
+}
+
+func (h *Handler) query(w http.ResponseWriter, r *http.Request) {
+	var request queryRequest
+	if !decode(w, r, &request) {
+		return
+	}
+	rng := prometheus.Range{Start: request.Range.From, End: request.Range.To, Step: step(request)}
+	response := make([]timeSeries, 0)
+	for _, target := range request.Targets {
+		level, query, err := parseTarget(target.Target)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if query == "" {
+			continue
+		}
+		series, err := h.source.QueryRange(r.Context(), query, rng)
+		if err != nil {
+			writeError(w, http.StatusBadGateway, err)
+			return
+		}
+		for _, s := range series {
+			levels, err := detectLevels(s.Samples, level)
+			if err != nil {
+				writeError(w, http.StatusUnprocessableEntity, fmt.Errorf("%s: %w", seriesName(s.Labels), err))
+				return
+			}
+			name := seriesName(s.Labels)
+			response = append(response, timeSeries{name, datapoints(s.Samples.Timestamps, s.Samples.Values)})
+			for _, l := range levels {
+				if level == 0 || l.level == level {
+					response = append(response, timeSeries{
+						fmt.Sprintf("%s level %d", name, l.level),
+						datapoints(s.Samples.Timestamps, l.inflated),
+					})
+				}
+			}
+		}
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+type annotationRequest struct {
+	Range      timeRange       `json:"range"`
+	Annotation json.RawMessage `json:"annotation"`
+}
+
+type annotation struct {
+	Annotation json.RawMessage `json:"annotation"`
+	Time       int64           `json:"time"`
+	Title      string          `json:"title"`
+	Text       string          `json:"text"`
+	Tags       []string        `json:"tags"`
+}
+
+func (h *Handler) annotations(w http.ResponseWriter, r *http.Request) {
+	var request annotationRequest
+	if !decode(w, r, &request) {
+		return
+	}
+	var query struct {
+		Query string `json:"query"`
+	}
+	if err := json.Unmarshal(request.Annotation, &query); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	level, expr, err := parseTarget(query.Query)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	response := make([]annotation, 0)
+	if expr == "" {
+		writeJSON(w, http.StatusOK, response)
+		return
+	}
+	rng := prometheus.Range{Start: request.Range.From, End: request.Range.To, Step: max(defaultStep, minStep(request.Range))}
+	series, err := h.source.QueryRange(r.Context(), expr, rng)
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+	for _, s := range series {
+		levels, err := detectLevels(s.Samples, level)
+		if err != nil {
+			writeError(w, http.StatusUnprocessableEntity, fmt.Errorf("%s: %w", seriesName(s.Labels), err))
+			return
+		}
+		if len(levels) == 0 || level != 0 && levels[len(levels)-1].level != level {
+			continue
+		}
+		l := levels[len(levels)-1]
+		name := seriesName(s.Labels)
+		for _, at := range l.peaks {
+			response = append(response, annotation{
+				Annotation: request.Annotation,
+				Time:       s.Samples.Timestamps[at].UnixMilli(),
+				Title:      fmt.Sprintf("Level %d peak", l.level),
+				Text:       fmt.Sprintf("%s: %s", name, strconv.FormatFloat(s.Samples.Values[at], 'g', -1, 64)),
+				Tags:       []string{fmt.Sprintf("level=%d", l.level)},
+			})
+		}
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+var targetPattern = regexp.MustCompile(`^\s*level\s*=\s*(\d+)\s+(.*)$`)
+
+// Splits the target into the level, which is 0 if it is not given, and the query.
+func parseTarget(target string) (int, string, error) {
+	match := targetPattern.FindStringSubmatch(target)
+	if match == nil {
+		return 0, strings.TrimSpace(target), nil
+	}
+	level, err := strconv.Atoi(match[1])
+	if err != nil || level < 1 {
+		return 0, "", fmt.Errorf("invalid level %q", match[1])
+	}
+	return level, strings.TrimSpace(match[2]), nil
+}
+
+// The resolution of a query is the one Grafana asks for, unless the range
+// is too long for it.
+func step(request queryRequest) time.Duration {
+	return max(requestedStep(request), minStep(request.Range))
+}
+
+func requestedStep(request queryRequest) time.Duration {
+	if request.IntervalMs > 0 {
+		return time.Duration(request.IntervalMs) * time.Millisecond
+	}
+	if request.MaxDataPoints > 0 {
+		if s := request.Range.To.Sub(request.Range.From) / time.Duration(request.MaxDataPoints); s >= time.Second {
+			return s
+		}
+		return time.Second
+	}
+	return defaultStep
+}
+
+// Returns the least step, in whole seconds, at which a query of the range
+// results in no more points, for each series, than Prometheus returns.
+func minStep(r timeRange) time.Duration {
+	intervals := time.Duration(maxPoints - 1)
+	s := (r.To.Sub(r.From) + intervals - 1) / intervals
+	if remainder := s % time.Second; remainder > 0 {
+		s += time.Second - remainder
+	}
+	return s
+}
+
+type level struct {
+	level    int
+	peaks    []int
+	inflated []float64
+}
+
+// Detects the peaks at each level, up to, and including, the specified
+// level, or up to the last level that has any peaks, if it is 0. A level
+// without any peaks is left out, and the detection stops there. The NaN
+// samples of the series are skipped, and the peaks of each level are
+// inflated into the original series, with their values.
+func detectLevels(s peakdetect.Series[float64], upTo int) ([]level, error) {
+	primary, err := peakdetect.DetectPeaksWithNaNPolicy[float64](s.Values, peakdetect.NaNSkip)
+	if err != nil {
+		return nil, err
+	}
+	if primary.GetPeakCount() == 0 {
+		return nil, nil
+	}
+	levels := []level{{1, primary.GetPeaks(), primary.Inflate()}}
+	// One iteration detects the secondary peaks, the same way two do.
+	for l := 2; upTo == 0 || l <= upTo; l++ {
+		secondary, _, err := peakdetect.IteratePeakDetectWithNaNPolicy[float64](uint(l), s.Values, peakdetect.NaNSkip)
+		if err != nil {
+			return nil, err
+		}
+		if secondary.GetLevel() < l || secondary.GetPeakCount() == 0 {
+			break
+		}
+		inflated := secondary.InflateWithCount(len(s.Values), peakdetect.PrimaryValuesOnly(&secondary))
+		levels = append(levels, level{l, secondary.GetPrimaryPeaks(), inflated})
+	}
+	return levels, nil
+}
+
+// Grafana expects each datapoint to be a pair of the value, and the time,
+// in milliseconds since the epoch.
+type datapoint [2]float64
+
+// JSON has no NaN, therefore, a NaN value is null, which Grafana takes to
+// be a missing value.
+func (p datapoint) MarshalJSON() ([]byte, error) {
+	if math.IsNaN(p[0]) {
+		return json.Marshal([2]any{nil, p[1]})
+	}
+	return json.Marshal([2]float64(p))
+}
+
+func datapoints(timestamps []time.Time, values []float64) []datapoint {
+	points := make([]datapoint, len(values))
+	for i, value := range values {
+		points[i] = datapoint{value, float64(timestamps[i].UnixMilli())}
+	}
+	return points
+}
+
+// Names the series the way Prometheus does, e.g., up{instance="a",job="b"}.
+func seriesName(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		if name != "__name__" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf("%s=%q", name, labels[name])
+	}
+	if len(pairs) == 0 && labels["__name__"] != "" {
+		return labels["__name__"]
+	}
+	return labels["__name__"] + "{" + strings.Join(pairs, ",") + "}"
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return false
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBytes)).Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+var _ Source = (*prometheus.Client)(nil)
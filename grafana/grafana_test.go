@@ -0,0 +1,268 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andr31g/peak-detector/peakdetect"
+	"github.com/andr31g/peak-detector/prometheus"
+)
+
+var start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Serves a single series, whatever the query, after checking the range.
+type source struct {
+	t      *testing.T
+	step   time.Duration
+	values []float64
+	err    error
+}
+
+func (s *source) QueryRange(ctx context.Context, query string, r prometheus.Range) ([]prometheus.Series, error) {
+	if query != "up" {
+		s.t.Errorf("expected query %q, got %q", "up", query)
+	}
+	if !r.Start.Equal(start) || r.Step != s.step {
+		s.t.Errorf("unexpected range %+v", r)
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	timestamps := make([]time.Time, len(s.values))
+	for i := range timestamps {
+		timestamps[i] = start.Add(time.Duration(i) * time.Minute)
+	}
+	series := peakdetect.CreateSeries[float64](timestamps, s.values)
+	return []prometheus.Series{{Labels: map[string]string{"__name__": "up", "job": "a"}, Samples: series}}, nil
+}
+
+func serve(t *testing.T, s *source, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	request := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	response := httptest.NewRecorder()
+	CreateHandler(s).ServeHTTP(response, request)
+	return response
+}
+
+func decodeResponse[T any](t *testing.T, response *httptest.ResponseRecorder) T {
+	t.Helper()
+	if response.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d, %s", http.StatusOK, response.Code, response.Body)
+	}
+	var v T
+	if err := json.Unmarshal(response.Body.Bytes(), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestTest(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	response := httptest.NewRecorder()
+	CreateHandler(&source{}).ServeHTTP(response, request)
+	if response.Code != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, response.Code)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	targets := decodeResponse[[]string](t, serve(t, &source{}, "/search", `{"target": "level=5 up"}`))
+	expected := []string{"up", "level=1 up", "level=2 up", "level=3 up"}
+	if !reflect.DeepEqual(expected, targets) {
+		t.Errorf("expected %v, got %v", expected, targets)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	values := []float64{1, 3, 2, 5, 1, 4, 2}
+	tests := []struct {
+		name     string
+		target   string
+		expected []timeSeries
+	}{
+		{
+			name:   "all levels",
+			target: "up",
+			expected: []timeSeries{
+				{`up{job="a"}`, points(values)},
+				{`up{job="a"} level 1`, points([]float64{0, 3, 0, 5, 0, 4, 0})},
+				{`up{job="a"} level 2`, points([]float64{0, 0, 0, 5, 0, 0, 0})},
+			},
+		},
+		{
+			name:   "one level",
+			target: "level=2 up",
+			expected: []timeSeries{
+				{`up{job="a"}`, points(values)},
+				{`up{job="a"} level 2`, points([]float64{0, 0, 0, 5, 0, 0, 0})},
+			},
+		},
+		{
+			name:   "level without peaks",
+			target: "level=3 up",
+			expected: []timeSeries{
+				{`up{job="a"}`, points(values)},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body := `{
+				"range": {"from": "2024-01-01T00:00:00Z", "to": "2024-01-01T00:06:00Z"},
+				"intervalMs": 60000,
+				"targets": [{"target": "` + test.target + `", "refId": "A"}]
+			}`
+			series := decodeResponse[[]timeSeries](t, serve(t, &source{t: t, step: time.Minute, values: values}, "/query", body))
+			if !reflect.DeepEqual(test.expected, series) {
+				t.Errorf("expected %v, got %v", test.expected, series)
+			}
+		})
+	}
+}
+
+// The NaN samples are skipped, and are null in the series itself.
+func TestQueryNaN(t *testing.T) {
+	body := `{
+		"range": {"from": "2024-01-01T00:00:00Z", "to": "2024-01-01T00:04:00Z"},
+		"intervalMs": 60000,
+		"targets": [{"target": "level=1 up", "refId": "A"}]
+	}`
+	values := []float64{1, 3, math.NaN(), 2, 1}
+	series := decodeResponse[[]struct {
+		Target     string        `json:"target"`
+		Datapoints [][2]*float64 `json:"datapoints"`
+	}](t, serve(t, &source{t: t, step: time.Minute, values: values}, "/query", body))
+	if len(series) != 2 {
+		t.Fatalf("expected the series, and its primary peaks, got %v", series)
+	}
+	for i, point := range series[0].Datapoints {
+		if (point[0] == nil) != math.IsNaN(values[i]) {
+			t.Errorf("expected the value at %d to be null only if it is NaN, got %v", i, point[0])
+		}
+	}
+	for i, point := range series[1].Datapoints {
+		if expected := []float64{0, 3, 0, 0, 0}[i]; point[0] == nil || *point[0] != expected {
+			t.Errorf("expected the peak series to be %v at %d, got %v", expected, i, point[0])
+		}
+	}
+}
+
+func TestAnnotations(t *testing.T) {
+	values := []float64{1, 3, 2, 5, 1, 4, 2}
+	tests := []struct {
+		query    string
+		expected []annotation
+	}{
+		{"level=1 up", []annotation{
+			{Time: start.Add(time.Minute).UnixMilli(), Title: "Level 1 peak", Text: `up{job="a"}: 3`, Tags: []string{"level=1"}},
+			{Time: start.Add(3 * time.Minute).UnixMilli(), Title: "Level 1 peak", Text: `up{job="a"}: 5`, Tags: []string{"level=1"}},
+			{Time: start.Add(5 * time.Minute).UnixMilli(), Title: "Level 1 peak", Text: `up{job="a"}: 4`, Tags: []string{"level=1"}},
+		}},
+		{"up", []annotation{
+			{Time: start.Add(3 * time.Minute).UnixMilli(), Title: "Level 2 peak", Text: `up{job="a"}: 5`, Tags: []string{"level=2"}},
+		}},
+		{"level=3 up", []annotation{}},
+	}
+	for _, test := range tests {
+		t.Run(test.query, func(t *testing.T) {
+			a := `{"name":"peaks","enable":true,"query":"` + test.query + `"}`
+			body := `{"range": {"from": "2024-01-01T00:00:00Z", "to": "2024-01-01T00:06:00Z"}, "annotation": ` + a + `}`
+			annotations := decodeResponse[[]annotation](t, serve(t, &source{t: t, step: defaultStep, values: values}, "/annotations", body))
+			for i := range test.expected {
+				test.expected[i].Annotation = json.RawMessage(a)
+			}
+			if !reflect.DeepEqual(test.expected, annotations) {
+				t.Errorf("expected %+v, got %+v", test.expected, annotations)
+			}
+		})
+	}
+}
+
+// However long the range, the step is such that Prometheus returns no more
+// points, for each series, than it allows.
+func TestStep(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		name    string
+		request queryRequest
+		step    time.Duration
+	}{
+		{"interval", queryRequest{IntervalMs: 60000}, time.Minute},
+		{"max data points", queryRequest{MaxDataPoints: 60}, time.Minute},
+		{"max data points under a second", queryRequest{MaxDataPoints: 10000}, time.Second},
+		{"default", queryRequest{}, defaultStep},
+		// 60 days over 10,999 intervals is just over 471 seconds.
+		{"interval over a long range", queryRequest{IntervalMs: 1000, Range: timeRange{start, start.Add(60 * day)}}, 472 * time.Second},
+		{"default over a long range", queryRequest{Range: timeRange{start, start.Add(60 * day)}}, 472 * time.Second},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if test.request.Range.To.IsZero() {
+				test.request.Range = timeRange{start, start.Add(time.Hour)}
+			}
+			s := step(test.request)
+			if s != test.step {
+				t.Errorf("expected %v, got %v", test.step, s)
+			}
+			if points := test.request.Range.To.Sub(test.request.Range.From)/s + 1; points > maxPoints {
+				t.Errorf("expected at most %d points, got %d", maxPoints, points)
+			}
+		})
+	}
+}
+
+// The annotations over a long range are at a step that keeps the points
+// within the limit, rather than at the default step.
+func TestAnnotationsLongRange(t *testing.T) {
+	a := `{"name":"peaks","enable":true,"query":"up"}`
+	body := `{"range": {"from": "2024-01-01T00:00:00Z", "to": "2024-03-01T00:00:00Z"}, "annotation": ` + a + `}`
+	// 60 days, as in TestStep.
+	annotations := decodeResponse[[]annotation](t, serve(t, &source{t: t, step: 472 * time.Second, values: []float64{1, 3, 2}}, "/annotations", body))
+	if len(annotations) != 1 {
+		t.Errorf("expected the peak of 3, got %+v", annotations)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	query := `{"range": {"from": "2024-01-01T00:00:00Z"}, "intervalMs": 60000, "targets": [{"target": "%s"}]}`
+	tests := []struct {
+		name   string
+		source *source
+		path   string
+		body   string
+		status int
+	}{
+		{"malformed", &source{}, "/query", `{"targets": [`, http.StatusBadRequest},
+		{"invalid level", &source{}, "/query", strings.Replace(query, "%s", "level=0 up", 1), http.StatusBadRequest},
+		{"source", &source{step: time.Minute, err: errors.New("unavailable")}, "/query", strings.Replace(query, "%s", "up", 1), http.StatusBadGateway},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			test.source.t = t
+			response := serve(t, test.source, test.path, test.body)
+			if response.Code != test.status {
+				t.Errorf("expected status %d, got %d, %s", test.status, response.Code, response.Body)
+			}
+		})
+	}
+}
+
+func points(values []float64) []datapoint {
+	points := make([]datapoint, len(values))
+	for i, value := range values {
+		points[i] = datapoint{value, float64(start.Add(time.Duration(i) * time.Minute).UnixMilli())}
+	}
+	return points
+}
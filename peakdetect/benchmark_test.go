@@ -0,0 +1,70 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import (
+	"math/rand"
+	"testing"
+)
+
+var benchmarkSizes = []struct {
+	name  string
+	count int
+}{
+	{"1K", 1_000},
+	{"1M", 1_000_000},
+	{"100M", 100_000_000},
+}
+
+func BenchmarkDetectPeaks(b *testing.B) {
+	benchmark(b, func(samples []int32) {
+		DetectPeaks[int32](samples)
+	})
+}
+
+func BenchmarkDetectPeaksByClusters(b *testing.B) {
+	benchmark(b, func(samples []int32) {
+		_, _ = detectPeaksByClusters[int32](samples)
+	})
+}
+
+func BenchmarkDetectPeaksParallel(b *testing.B) {
+	benchmark(b, func(samples []int32) {
+		DetectPeaksParallel[int32](samples, 0)
+	})
+}
+
+func BenchmarkIteratePeakDetectUntil(b *testing.B) {
+	benchmark(b, func(samples []int32) {
+		_, _, _ = IteratePeakDetectUntil[int32](samples, UntilPeakCount(10))
+	})
+}
+
+func benchmark(b *testing.B, detect func(samples []int32)) {
+	for _, size := range benchmarkSizes {
+		b.Run(size.name, func(b *testing.B) {
+			if size.count > 1_000_000 && testing.Short() {
+				b.Skip("skipping in short mode")
+			}
+			samples := benchmarkSamples(size.count)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				detect(samples)
+			}
+		})
+	}
+}
+
+// A random walk, such that the samples have peaks of all sizes, as well as
+// plateaus, since a third of the steps leave the sample as it is.
+func benchmarkSamples(count int) []int32 {
+	r := rand.New(rand.NewSource(1))
+	samples := make([]int32, count)
+	for i := 1; i < count; i++ {
+		samples[i] = samples[i-1] + int32(r.Intn(3)) - 1
+	}
+	return samples
+}
@@ -4,47 +4,126 @@
 
 package peakdetect
 
-/*
- Sketch of a concurrent merge operation (very much a WIP; still working on the concept...)
-
- Basic idea is that we split the stream into 'N' workers, each worker taking two buckets of 3 samples
- each, so, 2 * 3 = 6 samples per worker, so, we divide the whole stream, well, an arbitrary slice/array,
- or as I call it here, a cluster of samples, of the stream, by 6, to get the 'N' workers.
-
- num_clusters = 2
- samples_per_cluster = 3
-
- N = len(stream_slice) / (num_clusters * samples_per_cluster)
- N = len(stream_slice) / 6
-
- Each worker then, computes the 3 samples and merges them, producing a 6-sample cluster.
- When two nearby 6-sample clusters are available, they can be merged into one 12-sample cluster.
- When two nearby 12-sample clusters are available, they can be merged into one 24-sample cluster,
- and so on, and so forth, until only two clusters remain, in which case they're merged, or only
- a single cluster remains, in which case it is the result.
+import (
+	"runtime"
+	"slices"
+	"sync"
+)
 
- At the lowest level, we're always going to have 'N' workers. At the next level up, where the
- 6-sample clusters are merged... how many workers are we going to have? Half of 'N', i.e., 'N/2'?
- I need to think about this..
-
- UPDATE:
-
- At the lowest level of 'N' we run the 3-sample clusters. Then we merge all the workers.
- At the next level, when we merged all the 3-sample clusters, we have 6-sample clusters.
-
- Now, our:
+/*
+ Concurrent merge
 
- samples_per_cluster = 6
- num_clusters = 2
+ The basic idea is that we split the samples into 'N' buckets, one per worker. Each worker detects
+ the peaks in its bucket, the same way DetectPeaks does, i.e., it computes the 3-sample clusters and
+ merges them, producing one cluster of all the samples of the bucket.
 
- N_1 = len(stream_slice) / (2 * 6)
- N_1 = len(stream_slice) / 12
+ When two nearby clusters are available, they can be merged into one cluster, the same way any two
+ 3-sample clusters are merged. Therefore, at the next level up, we merge the 'N' clusters pairwise,
+ using 'N/2' workers, producing 'N/2' clusters, each twice the size. At the level after that, we use
+ 'N/4' workers, and so on, and so forth, until only a single cluster remains, which is the result.
 
- N_2 = len(stream_slice) / (2 * 12)
- N_2 = len(stream_slice) / 24
+ N_0 = N
+ N_1 = N / 2
+ N_2 = N / 4
+ N_m = N / 2^m
 
- N_m = len(stream_slice) / (2 * ..)
- N_m = len(stream_slice) / ..
+ The merge only looks at, and fixes up, the samples on the boundary of the two clusters, and these
+ are the same irrespective of how the samples were split. Therefore, the peaks are exactly the same
+ as those found by DetectPeaks.
 
+ Each bucket is at least 2 clusters of 3 samples, i.e., 6 samples, and the buckets are a multiple
+ of 3 samples, such that the 3-sample clusters line up with those of DetectPeaks.
 
+ Like DetectPeaks, the buckets are only ranges of the samples, and the merge only merges the peaks
+ of the ranges, rather than copy the samples of each bucket.
 */
+
+const samplesPerBucket = 2 * 3
+
+// DetectPeaksParallel returns empty peaks if any of the samples is NaN, and
+// therefore cannot be ordered. Use DetectPeaksParallelE to find out why.
+func DetectPeaksParallel[T Number](samples []T, workers int) PrimaryPeaks[T] {
+	peaks, _ := DetectPeaksParallelE[T](samples, workers)
+	return peaks
+}
+
+// DetectPeaksParallelE detects the same peaks as DetectPeaksE, but using up
+// to the specified number of workers concurrently. If the number of workers
+// is not positive, then as many workers are used as there are CPUs.
+func DetectPeaksParallelE[T Number](samples []T, workers int) (PrimaryPeaks[T], error) {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if err := findNaN[T](samples, nil, nil); err != nil {
+		return PrimaryPeaks[T]{}, err
+	}
+	bucketSize := (len(samples) + workers - 1) / workers
+	bucketSize = max(samplesPerBucket, (bucketSize+2)/3*3)
+	if bucketSize >= len(samples) {
+		return DetectPeaksE[T](samples)
+	}
+
+	type cluster struct {
+		start, end int
+		peaks      []int
+	}
+	clusters := make([]cluster, 0, (len(samples)+bucketSize-1)/bucketSize)
+	for at := 0; at < len(samples); at += bucketSize {
+		clusters = append(clusters, cluster{start: at, end: min(at+bucketSize, len(samples))})
+	}
+	err := concurrently(len(clusters), func(i int) error {
+		c := &clusters[i]
+		bucket, err := DetectPeaksE[T](samples[c.start:c.end])
+		if err != nil {
+			return clusterError(c.start, err)
+		}
+		c.peaks = bucket.peaks
+		for j := range c.peaks {
+			c.peaks[j] += c.start
+		}
+		return nil
+	})
+	if err != nil {
+		return PrimaryPeaks[T]{}, err
+	}
+
+	for len(clusters) > 1 {
+		merged := make([]cluster, (len(clusters)+1)/2)
+		err := concurrently(len(clusters)/2, func(i int) (err error) {
+			left, right := clusters[2*i], clusters[2*i+1]
+			merged[i] = cluster{start: left.start, end: right.end}
+			merged[i].peaks, err = mergeRanges[T](samples, left.peaks, left.start, left.end, right.peaks, right.end)
+			return err
+		})
+		if err != nil {
+			return PrimaryPeaks[T]{}, err
+		}
+		if len(clusters)%2 == 1 {
+			merged[len(merged)-1] = clusters[len(clusters)-1]
+		}
+		clusters = merged
+	}
+
+	return PrimaryPeaks[T]{slices.Clip(samples), clusters[0].peaks, 1}, nil
+}
+
+// Runs the function for each of the 'n' indices, each in its own goroutine,
+// and waits for all of them to complete, returning the first error, if any.
+func concurrently(n int, f func(i int) error) error {
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = f(i)
+		}(i)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
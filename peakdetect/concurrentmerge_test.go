@@ -0,0 +1,84 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Every way of splitting the samples into buckets, and of merging them, finds
+// the same peaks as DetectPeaks, since the merge only looks at the samples on
+// the boundary of the two clusters.
+func TestDetectPeaksParallel(t *testing.T) {
+	for numberOfPlaces := 1; numberOfPlaces <= 8; numberOfPlaces++ {
+		t.Run(fmt.Sprintf("places=%d", numberOfPlaces), func(t *testing.T) {
+			for _, samples := range product(t, []int{0, 1, 2}, numberOfPlaces) {
+				expectParallelPeaks(t, samples)
+			}
+		})
+	}
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 200; n++ {
+		samples := make([]int, r.Intn(1000))
+		for i := range samples {
+			samples[i] = r.Intn(1 + n%5)
+		}
+		expectParallelPeaks(t, samples)
+	}
+}
+
+func expectParallelPeaks(t *testing.T, samples []int) {
+	t.Helper()
+	expected := DetectPeaks(samples)
+	for _, workers := range []int{-1, 0, 1, 2, 3, 4, 7, 16, 1000} {
+		peaks, err := DetectPeaksParallelE(samples, workers)
+		if err != nil {
+			t.Fatalf("%v, %d workers: %v", samples, workers, err)
+		}
+		if !slices.Equal(expected.GetPeaks(), peaks.GetPeaks()) || peaks.GetLevel() != 1 {
+			t.Fatalf("%v, %d workers: expected %v, got %v, at level %d", samples, workers, expected.GetPeaks(), peaks.GetPeaks(), peaks.GetLevel())
+		}
+	}
+}
+
+// The buckets are detected, and merged, concurrently, over the same samples,
+// and must only ever read them. Run with -race.
+func TestDetectPeaksParallelRace(t *testing.T) {
+	samples := make([]float64, 100_000)
+	r := rand.New(rand.NewSource(1))
+	for i := range samples {
+		samples[i] = float64(r.Intn(10))
+	}
+	expected := DetectPeaks(samples)
+	done := make(chan PrimaryPeaks[float64])
+	for i := 0; i < 4; i++ {
+		go func() {
+			done <- DetectPeaksParallel(samples, 16)
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		if peaks := <-done; !slices.Equal(expected.GetPeaks(), peaks.GetPeaks()) {
+			t.Fatal("expected the same peaks as DetectPeaks")
+		}
+	}
+}
+
+func TestDetectPeaksParallelError(t *testing.T) {
+	samples := make([]float64, 1000)
+	samples[700] = math.NaN()
+	peaks, err := DetectPeaksParallelE(samples, 8)
+	if !errors.Is(err, ErrNaNSample) || !strings.Contains(err.Error(), "sample 700") {
+		t.Errorf("expected %v at sample 700, got %v", ErrNaNSample, err)
+	}
+	if peaks.GetPeakCount() != 0 {
+		t.Errorf("expected no peaks, got %v", peaks.GetPeaks())
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import "context"
+
+// Progress describes how far the detection of a level of the hierarchy has
+// come. Of the samples of the level, i.e., the original samples at level 1,
+// or the peaks of the level below, otherwise, so many have been processed,
+// and so many peaks remain among them.
+type Progress struct {
+	Level     int
+	Processed int
+	Samples   int
+	Peaks     int
+}
+
+// The number of clusters between the checks of the context, such that the
+// detection is not slowed down by checking it for every cluster.
+const clustersPerCheck = 4096
+
+// Checks whether the detection is to stop, when a level starts, and every so
+// many clusters, and reports the progress, if anyone is interested. A nil
+// monitor never stops the detection, such that the detection functions that
+// take no context are not slowed down either.
+type monitor struct {
+	ctx      context.Context
+	progress func(Progress)
+	current  Progress
+	clusters int
+}
+
+func createMonitor(ctx context.Context, progress func(Progress)) *monitor {
+	return &monitor{ctx: ctx, progress: progress}
+}
+
+func (m *monitor) start(level, samples int) error {
+	if m == nil {
+		return nil
+	}
+	m.current = Progress{Level: level, Samples: samples}
+	m.clusters = 0
+	return m.check()
+}
+
+// Called before the cluster starting at the specified sample of the level.
+func (m *monitor) cluster(processed, peaks int) error {
+	if m == nil {
+		return nil
+	}
+	if m.clusters++; m.clusters%clustersPerCheck != 0 {
+		return nil
+	}
+	m.current.Processed = processed
+	m.current.Peaks = peaks
+	return m.check()
+}
+
+func (m *monitor) done(peaks int) {
+	if m == nil {
+		return
+	}
+	m.current.Processed = m.current.Samples
+	m.current.Peaks = peaks
+	if m.progress != nil {
+		m.progress(m.current)
+	}
+}
+
+func (m *monitor) check() error {
+	if err := m.ctx.Err(); err != nil {
+		return err
+	}
+	if m.progress != nil {
+		m.progress(m.current)
+	}
+	return nil
+}
+
+// DetectPeaksContext is DetectPeaksE, which stops once the context is done,
+// returning the error of the context. The progress function, unless it is
+// nil, is called as the detection starts, every so many clusters, and once
+// it is done, i.e., with all the samples processed.
+func DetectPeaksContext[T Number](ctx context.Context, samples []T, progress func(Progress)) (PrimaryPeaks[T], error) {
+	return detectPeaks[T](samples, createMonitor(ctx, progress))
+}
+
+// DetectPeaksInPrimaryContext is DetectPeaksInPrimaryE, which stops, and
+// reports its progress, the same way DetectPeaksContext does.
+func DetectPeaksInPrimaryContext[T Number](ctx context.Context, p PrimaryPeaks[T], progress func(Progress)) (SecondaryPeaks[T], error) {
+	return detectPeaksInPrimary[T](p, createMonitor(ctx, progress))
+}
+
+// DetectPeaksInSecondaryContext is DetectPeaksInSecondaryE, which stops, and
+// reports its progress, the same way DetectPeaksContext does.
+func DetectPeaksInSecondaryContext[T Number](ctx context.Context, p SecondaryPeaks[T], progress func(Progress)) (SecondaryPeaks[T], error) {
+	return detectPeaksInSecondary[T](p, createMonitor(ctx, progress))
+}
+
+// IteratePeakDetectContext is IteratePeakDetectE, which stops, between the
+// levels, as well as between the clusters of each level, once the context is
+// done, and reports the progress of each level, the same way DetectPeaksContext
+// does.
+func IteratePeakDetectContext[T Number](ctx context.Context, iterations uint, samples []T, progress func(Progress)) (SecondaryPeaks[T], bool, error) {
+	return iteratePeakDetectWith[T](iterations, samples, createMonitor(ctx, progress))
+}
+
+// IteratePeakDetectToCompletionContext is IteratePeakDetectToCompletionE,
+// which stops, and reports its progress, the same way IteratePeakDetectContext
+// does.
+func IteratePeakDetectToCompletionContext[T Number](ctx context.Context, samples []T, progress func(Progress)) (SecondaryPeaks[T], error) {
+	return iteratePeakDetectToCompletion[T](samples, nil, createMonitor(ctx, progress))
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func randomWalk(n int) []int32 {
+	r := rand.New(rand.NewSource(1))
+	samples := make([]int32, n)
+	for i := 1; i < n; i++ {
+		samples[i] = samples[i-1] + int32(r.Intn(7)) - 3
+	}
+	return samples
+}
+
+func TestIteratePeakDetectContext(t *testing.T) {
+	samples := randomWalk(100_000)
+	var progress []Progress
+	secondary, ok, err := IteratePeakDetectContext(context.Background(), 4, samples, func(p Progress) {
+		progress = append(progress, p)
+	})
+	if err != nil || !ok {
+		t.Fatal(ok, err)
+	}
+	expected, _ := IteratePeakDetect(4, samples)
+	if !slices.Equal(expected.GetPrimaryPeaks(), secondary.GetPrimaryPeaks()) {
+		t.Errorf("expected the same peaks as IteratePeakDetect")
+	}
+
+	// Each level is reported as it starts, as it goes, and once it is done,
+	// at which point its peaks are the samples of the next level.
+	level, samplesAtLevel := 0, len(samples)
+	for i, p := range progress {
+		if p.Processed == 0 && p.Peaks == 0 {
+			if p.Level != level+1 || p.Samples != samplesAtLevel {
+				t.Fatalf("%d: unexpected start of a level %+v", i, p)
+			}
+			level = p.Level
+			continue
+		}
+		if p.Level != level || p.Samples != samplesAtLevel || p.Processed > p.Samples || p.Peaks > p.Processed {
+			t.Fatalf("%d: unexpected progress %+v", i, p)
+		}
+		if p.Processed == p.Samples {
+			samplesAtLevel = p.Peaks
+		}
+	}
+	if level != 4 {
+		t.Errorf("expected 4 levels, got %d", level)
+	}
+	if last := progress[len(progress)-1]; last.Processed != last.Samples || last.Peaks != secondary.GetPeakCount() {
+		t.Errorf("expected the last level to be done, got %+v", last)
+	}
+	if len(progress) <= 2*4 {
+		t.Errorf("expected progress within the primary level, got %v", progress)
+	}
+}
+
+func TestIteratePeakDetectToCompletionContext(t *testing.T) {
+	samples := randomWalk(10_000)
+	secondary, err := IteratePeakDetectToCompletionContext(context.Background(), samples, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := IteratePeakDetectToCompletion(samples)
+	if expected.GetLevel() != secondary.GetLevel() || !slices.Equal(expected.GetPrimaryPeaks(), secondary.GetPrimaryPeaks()) {
+		t.Errorf("expected the same peaks as IteratePeakDetectToCompletion")
+	}
+}
+
+// The detection stops within the level at which the context is canceled.
+func TestIteratePeakDetectContextCanceled(t *testing.T) {
+	samples := randomWalk(100_000)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var levels []int
+	_, err := IteratePeakDetectToCompletionContext(ctx, samples, func(p Progress) {
+		levels = append(levels, p.Level)
+		if p.Processed > 0 && p.Processed < p.Samples {
+			cancel()
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+	if levels[len(levels)-1] != 1 {
+		t.Errorf("expected the primary level to be interrupted, got %v", levels)
+	}
+
+	if _, err := DetectPeaksContext(ctx, []int{1, 3, 2}, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	primary := DetectPeaks([]int{1, 3, 2, 5, 1, 4, 2})
+	if _, err := DetectPeaksInPrimaryContext(ctx, primary, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	secondary := DetectPeaksInPrimary(primary)
+	if _, err := DetectPeaksInSecondaryContext(ctx, secondary, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+}
@@ -5,83 +5,202 @@
 package peakdetect
 
 import (
-	"errors"
 	"fmt"
-	"os"
+	"slices"
 )
 
 func peakDetectSample[T Number](a T) PrimaryPeaks[T] {
 	return CreatePeaksWith[T]([]T{a}, []int{})
 }
 
-func peakDetectPair[T Number](a, b T) PrimaryPeaks[T] {
-	if err, result := peakDetectPair0[T](a, b); err == nil {
-		return result
-	} else {
-		fmt.Println(err)
-		os.Exit(1)
+func peakDetectPair[T Number](a, b T) (PrimaryPeaks[T], error) {
+	err, result := peakDetectPair0[T](a, b)
+	return result, err
+}
+
+func peakDetectTriple[T Number](a, b, c T) (PrimaryPeaks[T], error) {
+	err, result := peakDetectTriple0[T](a, b, c)
+	return result, err
+}
+
+func peakDetectThreeSamples[T Number](samples []T) (PrimaryPeaks[T], error) {
+	return peakDetectTriple[T](samples[0], samples[1], samples[2])
+}
+
+// Detects the peaks among the one, two, or three samples in the range from
+// 'at' to 'end', exclusive, and appends their indices to 'peaks', without
+// copying the samples. The samples are classified by how they compare to one
+// another, the same way peakDetectPair0 and peakDetectTriple0 classify them.
+func peakDetectRange[T Number](samples []T, at, end int, peaks []int) ([]int, error) {
+	switch end - at {
+	case 1:
+		return peaks, nil
+	case 2:
+		if samples[at] > samples[at+1] {
+			return append(peaks, at), nil
+		} else if samples[at] < samples[at+1] {
+			return append(peaks, at+1), nil
+		}
+		return peaks, nil
+	default:
+		class := tripleClasses[tripleOrdering[T](samples[at], samples[at+1], samples[at+2])]
+		if class.err != nil {
+			return nil, class.err
+		}
+		for i := 0; i < 3; i++ {
+			if class.peaks&(1<<i) != 0 {
+				peaks = append(peaks, at+i)
+			}
+		}
+		return peaks, nil
 	}
-	return PrimaryPeaks[T]{}
 }
 
-func peakDetectTriple[T Number](a, b, c T) PrimaryPeaks[T] {
-	if err, result := peakDetectTriple0[T](a, b, c); err == nil {
-		return result
-	} else {
-		fmt.Println(err)
-		os.Exit(1)
+type tripleClass struct {
+	// The bit 'i' is set, if the sample 'i' is a peak.
+	peaks uint8
+	err   error
+}
+
+// The class of each of the 27 ways in which 'a' relates to 'b', 'b' to 'c',
+// and 'c' to 'a', as classified by peakDetectTriple0. The orderings that
+// samples which can be ordered cannot be in, are impossible states.
+var tripleClasses = classifyTriples()
+
+func classifyTriples() [27]tripleClass {
+	var classes [27]tripleClass
+	for i := range classes {
+		classes[i].err = ErrImpossibleState
+	}
+	for a := 0; a < 3; a++ {
+		for b := 0; b < 3; b++ {
+			for c := 0; c < 3; c++ {
+				err, p := peakDetectTriple0[int](a, b, c)
+				class := tripleClass{err: err}
+				for _, at := range p.peaks {
+					class.peaks |= 1 << at
+				}
+				classes[tripleOrdering[int](a, b, c)] = class
+			}
+		}
 	}
-	return PrimaryPeaks[T]{}
+	return classes
 }
 
-func peakDetectThreeSamples[T Number](samples []T) PrimaryPeaks[T] {
-	return peakDetectTriple[T](samples[0], samples[1], samples[2])
+// Each pair of samples is either less, greater, or neither, i.e., equal, the
+// same way the branches of peakDetectTriple0 tell them apart.
+func tripleOrdering[T Number](a, b, c T) int {
+	return 9*ordering[T](a, b) + 3*ordering[T](b, c) + ordering[T](c, a)
+}
+
+func ordering[T Number](x, y T) int {
+	if x < y {
+		return 0
+	} else if x > y {
+		return 1
+	}
+	return 2
 }
 
+// DetectPeaks returns empty peaks if any of the samples is NaN, and
+// therefore cannot be ordered. Use DetectPeaksE to find out why. The
+// peaks refer to the samples, as those of DetectPeaksE do.
 func DetectPeaks[T Number](samples []T) PrimaryPeaks[T] {
-	at := 0
-	stride := 3
-	left := PrimaryPeaks[T]{}
+	peaks, _ := DetectPeaksE[T](samples)
+	return peaks
+}
+
+// DetectPeaksE detects the peaks in place, i.e., the peaks refer to the
+// samples, rather than to a copy of them, such that any change made to the
+// samples is seen through the peaks. Therefore, the samples must not be
+// modified for as long as the peaks are in use, or else must be cloned
+// before they are passed in.
+//
+// The samples are clustered in triples, and each cluster is merged into the
+// peaks found so far as a range of the samples, rather than as a copy of
+// them. Therefore, only the peaks are allocated.
+func DetectPeaksE[T Number](samples []T) (PrimaryPeaks[T], error) {
+	return detectPeaks[T](samples, nil)
+}
 
-	for i := 0; at+stride <= len(samples); i++ {
+func detectPeaks[T Number](samples []T, m *monitor) (PrimaryPeaks[T], error) {
+	if err := m.start(1, len(samples)); err != nil {
+		return PrimaryPeaks[T]{}, err
+	}
+	if err := findNaN[T](samples, nil, nil); err != nil {
+		return PrimaryPeaks[T]{}, err
+	}
+	peaks := make([]int, 0)
+	var cluster [3]int
+	for at := 0; at < len(samples); at += 3 {
+		if err := m.cluster(at, len(peaks)); err != nil {
+			return PrimaryPeaks[T]{}, err
+		}
+		end := min(at+3, len(samples))
+		right, err := peakDetectRange[T](samples, at, end, cluster[:0])
+		if err != nil {
+			return PrimaryPeaks[T]{}, clusterError(at, err)
+		}
 		if at > 0 {
-			right := peakDetectTriple[T](samples[at], samples[at+1], samples[at+2])
-			left = merge[T](left, right)
+			if peaks, err = mergeRanges[T](samples, peaks, 0, at, right, end); err != nil {
+				return PrimaryPeaks[T]{}, clusterError(at, err)
+			}
 		} else {
-			left = peakDetectTriple[T](samples[i], samples[i+1], samples[i+2])
+			peaks = append(peaks, right...)
 		}
-		at += stride
 	}
+	m.done(len(peaks))
+	return PrimaryPeaks[T]{slices.Clip(samples), peaks, 1}, nil
+}
 
-	if len(samples)-at == 1 {
-		left = merge[T](left, peakDetectSample[T](samples[at]))
-	} else if len(samples)-at == 2 {
-		left = merge[T](left, peakDetectPair[T](samples[at], samples[at+1]))
-	}
+// DetectPeaksInPrimary returns empty peaks if any of the peaks is NaN, and
+// therefore cannot be ordered. Use DetectPeaksInPrimaryE to find out why.
+func DetectPeaksInPrimary[T Number](p PrimaryPeaks[T]) SecondaryPeaks[T] {
+	peaks, _ := DetectPeaksInPrimaryE[T](p)
+	return peaks
+}
 
-	return left
+func DetectPeaksInPrimaryE[T Number](p PrimaryPeaks[T]) (SecondaryPeaks[T], error) {
+	return detectPeaksInPrimary[T](p, nil)
 }
 
-func DetectPeaksInPrimary[T Number](p PrimaryPeaks[T]) SecondaryPeaks[T] {
+func detectPeaksInPrimary[T Number](p PrimaryPeaks[T], m *monitor) (SecondaryPeaks[T], error) {
+	if err := m.start(2, len(p.peaks)); err != nil {
+		return SecondaryPeaks[T]{}, err
+	}
+	if err := findNaN[T](p.samples, p.peaks, p.peaks); err != nil {
+		return SecondaryPeaks[T]{}, err
+	}
 	at := 0
 	stride := 3
 	left := SecondaryPeaks[T]{}
 
 	for i := 0; at+stride <= len(p.peaks); i++ {
+		if err := m.cluster(at, left.GetPeakCount()); err != nil {
+			return SecondaryPeaks[T]{}, err
+		}
 		if at > 0 {
 			a := p.peaks[at]
 			b := p.peaks[at+1]
 			c := p.peaks[at+2]
 			originalPeaks := []int{a, b, c}
-			rightTriple := peakDetectTriple[T](p.samples[a], p.samples[b], p.samples[c])
+			rightTriple, err := peakDetectTriple[T](p.samples[a], p.samples[b], p.samples[c])
+			if err != nil {
+				return SecondaryPeaks[T]{}, clusterError(a, err)
+			}
 			right := CreateSecondaryPeaksWith[T](rightTriple, alignPrimaryPeaks[T](rightTriple, originalPeaks), originalPeaks)
-			left = mergeSecondary[T](left, right)
+			if left, err = mergeSecondary[T](left, right); err != nil {
+				return SecondaryPeaks[T]{}, clusterError(a, err)
+			}
 		} else {
 			a := p.peaks[i]
 			b := p.peaks[i+1]
 			c := p.peaks[i+2]
 			originalPeaks := []int{a, b, c}
-			leftTriple := peakDetectTriple[T](p.samples[a], p.samples[b], p.samples[c])
+			leftTriple, err := peakDetectTriple[T](p.samples[a], p.samples[b], p.samples[c])
+			if err != nil {
+				return SecondaryPeaks[T]{}, clusterError(a, err)
+			}
 			left = CreateSecondaryPeaksWith[T](leftTriple, alignPrimaryPeaks[T](leftTriple, originalPeaks), originalPeaks)
 		}
 		at += stride
@@ -92,39 +211,81 @@ func DetectPeaksInPrimary[T Number](p PrimaryPeaks[T]) SecondaryPeaks[T] {
 		originalPeaks := []int{a}
 		rightSample := peakDetectSample[T](p.samples[a])
 		right := CreateSecondaryPeaksWith[T](rightSample, alignPrimaryPeaks[T](rightSample, originalPeaks), originalPeaks)
-		left = mergeSecondary[T](left, right)
+		var err error
+		if left, err = mergeSecondary[T](left, right); err != nil {
+			return SecondaryPeaks[T]{}, clusterError(a, err)
+		}
 	} else if len(p.peaks)-at == 2 {
 		a := p.peaks[at]
 		b := p.peaks[at+1]
 		originalPeaks := []int{a, b}
-		rightPair := peakDetectPair[T](p.samples[a], p.samples[b])
+		rightPair, err := peakDetectPair[T](p.samples[a], p.samples[b])
+		if err != nil {
+			return SecondaryPeaks[T]{}, clusterError(a, err)
+		}
 		right := CreateSecondaryPeaksWith[T](rightPair, alignPrimaryPeaks[T](rightPair, originalPeaks), originalPeaks)
-		left = mergeSecondary[T](left, right)
+		if left, err = mergeSecondary[T](left, right); err != nil {
+			return SecondaryPeaks[T]{}, clusterError(a, err)
+		}
 	}
 
 	left.primarySamples = p.samples
-	return left
+	left.level = 2
+	m.done(left.GetPeakCount())
+	return left, nil
 }
 
+// DetectPeaksInSecondary returns empty peaks if any of the peaks is NaN, and
+// therefore cannot be ordered. Use DetectPeaksInSecondaryE to find out why.
 func DetectPeaksInSecondary[T Number](p SecondaryPeaks[T]) SecondaryPeaks[T] {
+	peaks, _ := DetectPeaksInSecondaryE[T](p)
+	return peaks
+}
+
+// The original peaks of each cluster are the primary peaks of the previous
+// level, i.e., the original indices of the samples of the cluster. They are
+// copied, since the merge appends to them, and would otherwise overwrite
+// the primary peaks of the previous level, which are yet to be clustered.
+func DetectPeaksInSecondaryE[T Number](p SecondaryPeaks[T]) (SecondaryPeaks[T], error) {
+	return detectPeaksInSecondary[T](p, nil)
+}
+
+func detectPeaksInSecondary[T Number](p SecondaryPeaks[T], m *monitor) (SecondaryPeaks[T], error) {
+	if err := m.start(p.level+1, len(p.peaks)); err != nil {
+		return SecondaryPeaks[T]{}, err
+	}
+	if err := findNaN[T](p.samples, p.peaks, p.primaryPeaks); err != nil {
+		return SecondaryPeaks[T]{}, err
+	}
 	at := 0
 	stride := 3
 	left := SecondaryPeaks[T]{}
 
 	for i := 0; at+stride <= len(p.peaks); i++ {
+		if err := m.cluster(at, left.GetPeakCount()); err != nil {
+			return SecondaryPeaks[T]{}, err
+		}
 		if at > 0 {
 			a := p.peaks[at]
 			b := p.peaks[at+1]
 			c := p.peaks[at+2]
-			rightTriple := peakDetectTriple[T](p.samples[a], p.samples[b], p.samples[c])
-			right := CreateSecondaryPeaksWith[T](rightTriple, alignPrimaryPeaks[T](rightTriple, p.primaryPeaks[at:at+stride]), p.originalPeaks[at:at+stride])
-			left = mergeSecondary[T](left, right)
+			rightTriple, err := peakDetectTriple[T](p.samples[a], p.samples[b], p.samples[c])
+			if err != nil {
+				return SecondaryPeaks[T]{}, clusterError(p.primaryPeaks[at], err)
+			}
+			right := CreateSecondaryPeaksWith[T](rightTriple, alignPrimaryPeaks[T](rightTriple, p.primaryPeaks[at:at+stride]), slices.Clone(p.primaryPeaks[at:at+stride]))
+			if left, err = mergeSecondary[T](left, right); err != nil {
+				return SecondaryPeaks[T]{}, clusterError(p.primaryPeaks[at], err)
+			}
 		} else {
 			a := p.peaks[i]
 			b := p.peaks[i+1]
 			c := p.peaks[i+2]
-			leftTriple := peakDetectTriple[T](p.samples[a], p.samples[b], p.samples[c])
-			left = CreateSecondaryPeaksWith[T](leftTriple, alignPrimaryPeaks[T](leftTriple, p.primaryPeaks[:stride]), p.originalPeaks[:stride])
+			leftTriple, err := peakDetectTriple[T](p.samples[a], p.samples[b], p.samples[c])
+			if err != nil {
+				return SecondaryPeaks[T]{}, clusterError(p.primaryPeaks[i], err)
+			}
+			left = CreateSecondaryPeaksWith[T](leftTriple, alignPrimaryPeaks[T](leftTriple, p.primaryPeaks[:stride]), slices.Clone(p.primaryPeaks[:stride]))
 		}
 		at += stride
 	}
@@ -132,45 +293,130 @@ func DetectPeaksInSecondary[T Number](p SecondaryPeaks[T]) SecondaryPeaks[T] {
 	if len(p.peaks)-at == 1 {
 		a := p.peaks[at]
 		rightSample := peakDetectSample[T](p.samples[a])
-		right := CreateSecondaryPeaksWith[T](rightSample, alignPrimaryPeaks[T](rightSample, p.primaryPeaks[len(p.primaryPeaks)-1:]), p.originalPeaks[len(p.originalPeaks)-1:])
-		left = mergeSecondary[T](left, right)
+		right := CreateSecondaryPeaksWith[T](rightSample, alignPrimaryPeaks[T](rightSample, p.primaryPeaks[len(p.primaryPeaks)-1:]), slices.Clone(p.primaryPeaks[len(p.primaryPeaks)-1:]))
+		var err error
+		if left, err = mergeSecondary[T](left, right); err != nil {
+			return SecondaryPeaks[T]{}, clusterError(p.primaryPeaks[at], err)
+		}
 	} else if len(p.peaks)-at == 2 {
 		a := p.peaks[at]
 		b := p.peaks[at+1]
-		rightPair := peakDetectPair[T](p.samples[a], p.samples[b])
-		right := CreateSecondaryPeaksWith[T](rightPair, alignPrimaryPeaks[T](rightPair, p.primaryPeaks[len(p.primaryPeaks)-2:]), p.originalPeaks[len(p.originalPeaks)-2:])
-		left = mergeSecondary[T](left, right)
+		rightPair, err := peakDetectPair[T](p.samples[a], p.samples[b])
+		if err != nil {
+			return SecondaryPeaks[T]{}, clusterError(p.primaryPeaks[at], err)
+		}
+		right := CreateSecondaryPeaksWith[T](rightPair, alignPrimaryPeaks[T](rightPair, p.primaryPeaks[len(p.primaryPeaks)-2:]), slices.Clone(p.primaryPeaks[len(p.primaryPeaks)-2:]))
+		if left, err = mergeSecondary[T](left, right); err != nil {
+			return SecondaryPeaks[T]{}, clusterError(p.primaryPeaks[at], err)
+		}
 	}
 
 	left.primarySamples = p.primarySamples
-	return left
+	left.level = p.level + 1
+	m.done(left.GetPeakCount())
+	return left, nil
 }
 
 func IteratePeakDetectToCompletion[T Number](samples []T) SecondaryPeaks[T] {
-	primary := DetectPeaks[T](samples)
-	secondary := DetectPeaksInPrimary[T](primary)
+	secondary, _ := IteratePeakDetectToCompletionE[T](samples)
+	return secondary
+}
+
+func IteratePeakDetectToCompletionE[T Number](samples []T) (SecondaryPeaks[T], error) {
+	return iteratePeakDetectToCompletion[T](samples, nil, nil)
+}
+
+// The 'visit' function, if any, is called with the original indices of the
+// peaks at every level, starting with the primary peaks at level 1, up to,
+// and including, the last level that still has any peaks.
+func iteratePeakDetectToCompletion[T Number](samples []T, visit func(level int, peaks []int), m *monitor) (SecondaryPeaks[T], error) {
+	primary, err := detectPeaks[T](samples, m)
+	if err != nil {
+		return SecondaryPeaks[T]{}, err
+	}
+	level := 1
+	if visit != nil {
+		visit(level, primary.peaks)
+	}
+	secondary, err := detectPeaksInPrimary[T](primary, m)
+	if err != nil {
+		return SecondaryPeaks[T]{}, err
+	}
 	for secondary.GetPeakCount() > 0 {
-		secondary = DetectPeaksInSecondary[T](secondary)
+		level++
+		if visit != nil {
+			visit(level, secondary.primaryPeaks)
+		}
+		if secondary, err = detectPeaksInSecondary[T](secondary, m); err != nil {
+			return SecondaryPeaks[T]{}, err
+		}
 	}
-	return secondary
+	return secondary, nil
 }
 
 func IteratePeakDetect[T Number](iterations uint, samples []T) (SecondaryPeaks[T], bool) {
+	secondary, ok, _ := IteratePeakDetectE[T](iterations, samples)
+	return secondary, ok
+}
+
+func IteratePeakDetectE[T Number](iterations uint, samples []T) (SecondaryPeaks[T], bool, error) {
+	return iteratePeakDetectWith[T](iterations, samples, nil)
+}
+
+func iteratePeakDetectWith[T Number](iterations uint, samples []T, m *monitor) (SecondaryPeaks[T], bool, error) {
 	if iterations == 0 {
-		return SecondaryPeaks[T]{}, false
+		return SecondaryPeaks[T]{}, false, nil
+	}
+	primary, err := detectPeaks[T](samples, m)
+	if err != nil {
+		return SecondaryPeaks[T]{}, false, err
+	}
+	secondary, err := detectPeaksInPrimary[T](primary, m)
+	if err != nil {
+		return SecondaryPeaks[T]{}, false, err
 	}
-	primary := DetectPeaks[T](samples)
-	secondary := DetectPeaksInPrimary[T](primary)
 	if iterations == 1 {
-		return secondary, true
+		return secondary, true, nil
 	} else {
 		iterations--
 	}
 	for iterations > 1 && secondary.GetPeakCount() > 0 {
-		secondary = DetectPeaksInSecondary[T](secondary)
+		if secondary, err = detectPeaksInSecondary[T](secondary, m); err != nil {
+			return SecondaryPeaks[T]{}, false, err
+		}
 		iterations--
 	}
-	return secondary, true
+	return secondary, true, nil
+}
+
+// A NaN sample is neither less, greater, nor equal to its neighbors, and
+// only some of the ways in which it compares to them are impossible states.
+// The rest are taken for ordinary clusters, e.g., the one of [1, NaN, 1] for
+// the one of [1, 1, 1]. Therefore, the NaN samples are looked for up front.
+// The samples are either all of them, if the peaks are nil, or those of the
+// peaks, in which case the error reports the original index of the sample.
+// Samples of integer types are never NaN.
+func findNaN[T Number](samples []T, peaks, originalPeaks []int) error {
+	if peaks == nil {
+		for i, sample := range samples {
+			if sample != sample {
+				return fmt.Errorf("sample %d: %w", i, ErrNaNSample)
+			}
+		}
+		return nil
+	}
+	for i, at := range peaks {
+		if sample := samples[at]; sample != sample {
+			return fmt.Errorf("sample %d: %w", originalPeaks[i], ErrNaNSample)
+		}
+	}
+	return nil
+}
+
+// The error reports the index of the first original sample
+// of the cluster, in which detection was found to be failing.
+func clusterError(at int, err error) error {
+	return fmt.Errorf("cluster at sample %d: %w", at, err)
 }
 
 func alignPrimaryPeaks[T Number](p PrimaryPeaks[T], originalPeaks []int) []int {
@@ -213,7 +459,7 @@ func peakDetectTriple0[T Number](a, b, c T) (error, PrimaryPeaks[T]) {
 				// However, in this case, it is also said that 'b' is less
 				// than 'c',  which is a contradiction.
 				// Therefore, this is an impossible state.
-				return errors.New("impossible state"), PrimaryPeaks[T]{}
+				return ErrImpossibleState, PrimaryPeaks[T]{}
 			} else if c > a {
 				// (1) Peak at: {2}
 				//
@@ -236,7 +482,7 @@ func peakDetectTriple0[T Number](a, b, c T) (error, PrimaryPeaks[T]) {
 				// than 'a'. But in this case, 'b' is said to be greater than 'a',
 				// which is a contradiction.
 				// Therefore, this is an impossible state.
-				return errors.New("impossible state"), PrimaryPeaks[T]{}
+				return ErrImpossibleState, PrimaryPeaks[T]{}
 			}
 		} else if b > c {
 			if c < a {
@@ -280,7 +526,7 @@ func peakDetectTriple0[T Number](a, b, c T) (error, PrimaryPeaks[T]) {
 				// However, in this case, 'a' is defined to be less than 'b',
 				// and therefore cannot equal to 'b', by definition.
 				// Therefore, this is an impossible state.
-				return errors.New("impossible state"), PrimaryPeaks[T]{}
+				return ErrImpossibleState, PrimaryPeaks[T]{}
 			} else if c > a {
 				// (7) Peaks at: {1, 2}
 				//
@@ -299,7 +545,7 @@ func peakDetectTriple0[T Number](a, b, c T) (error, PrimaryPeaks[T]) {
 				// are also equal, then 'a' and 'b' must also be equal.
 				// However, in this case, 'a' must be less than 'b'.
 				// Therefore, this is an impossible state.
-				return errors.New("impossible state"), PrimaryPeaks[T]{}
+				return ErrImpossibleState, PrimaryPeaks[T]{}
 			}
 		}
 	} else if a > b {
@@ -357,7 +603,7 @@ func peakDetectTriple0[T Number](a, b, c T) (error, PrimaryPeaks[T]) {
 				// 'a' must also be bigger than 'c', since it is bigger
 				// than 'b', which is bigger than 'c'.
 				// Therefore, this is an impossible state.
-				return errors.New("impossible state"), PrimaryPeaks[T]{}
+				return ErrImpossibleState, PrimaryPeaks[T]{}
 			} else /* c == a */ {
 				// (14) impossible state
 				//
@@ -372,7 +618,7 @@ func peakDetectTriple0[T Number](a, b, c T) (error, PrimaryPeaks[T]) {
 				// by definition, and since 'a' is bigger than 'b', then so
 				// must 'c' be also, by definition, be bigger than 'b'.
 				// Therefore, this is an impossible state.
-				return errors.New("impossible state"), PrimaryPeaks[T]{}
+				return ErrImpossibleState, PrimaryPeaks[T]{}
 			}
 		} else /* b == c */ {
 			if c < a {
@@ -398,7 +644,7 @@ func peakDetectTriple0[T Number](a, b, c T) (error, PrimaryPeaks[T]) {
 				// this state, 'c' is said to be bigger than 'a', which is a
 				// contradiction.
 				// Therefore, this is an impossible state.
-				return errors.New("impossible state"), PrimaryPeaks[T]{}
+				return ErrImpossibleState, PrimaryPeaks[T]{}
 			} else /* c == a */ {
 				// (17) impossible state
 				//
@@ -415,7 +661,7 @@ func peakDetectTriple0[T Number](a, b, c T) (error, PrimaryPeaks[T]) {
 				// cannot be bigger than neither 'b' nor 'c', since again, the two
 				// are equal.
 				// Therefore, this is an impossible state.
-				return errors.New("impossible state"), PrimaryPeaks[T]{}
+				return ErrImpossibleState, PrimaryPeaks[T]{}
 			}
 		}
 	} else /* a == b */ {
@@ -434,7 +680,7 @@ func peakDetectTriple0[T Number](a, b, c T) (error, PrimaryPeaks[T]) {
 				// because 'a' is said to also be less than 'c', by way of being equal to 'b',
 				// which is said to be less than 'c', by definition.
 				// Therefore, this is an impossible state.
-				return errors.New("impossible state"), PrimaryPeaks[T]{}
+				return ErrImpossibleState, PrimaryPeaks[T]{}
 			} else if c > a {
 				// (19) Peak at: {2}
 				//
@@ -457,7 +703,7 @@ func peakDetectTriple0[T Number](a, b, c T) (error, PrimaryPeaks[T]) {
 				// 'a' is equal to be', 'c' must then also be equal to 'b'. However,
 				// this case states that 'b' is less than 'c', which is a contradiction.
 				// Therefore, this state is impossible.
-				return errors.New("impossible state"), PrimaryPeaks[T]{}
+				return ErrImpossibleState, PrimaryPeaks[T]{}
 			}
 		} else if b > c {
 			if c < a {
@@ -482,7 +728,7 @@ func peakDetectTriple0[T Number](a, b, c T) (error, PrimaryPeaks[T]) {
 				// but since 'a' and 'b' are equal, then this says that 'c' is also
 				// greater than 'b', which is a contradiction.
 				// Therefore, this is an impossible state.
-				return errors.New("impossible state"), PrimaryPeaks[T]{}
+				return ErrImpossibleState, PrimaryPeaks[T]{}
 			} else /* c == a */ {
 				// (23) impossible state
 				//
@@ -495,7 +741,7 @@ func peakDetectTriple0[T Number](a, b, c T) (error, PrimaryPeaks[T]) {
 				// Therefore, transitively, 'b' is equal to 'c'. However, in this
 				// case, 'b' is said to be greater than 'c', which is a contradiction.
 				// Therefore, this is an impossible state.
-				return errors.New("impossible state"), PrimaryPeaks[T]{}
+				return ErrImpossibleState, PrimaryPeaks[T]{}
 			}
 		} else /* b == c */ {
 			if c < a {
@@ -508,7 +754,7 @@ func peakDetectTriple0[T Number](a, b, c T) (error, PrimaryPeaks[T]) {
 				// In this case, 'a' is equal to 'b', and 'b' is equal to 'c'.
 				// However, 'c' is also said to be less than 'a', which is a contradiction.
 				// Therefore, this is an impossible state.
-				return errors.New("impossible state"), PrimaryPeaks[T]{}
+				return ErrImpossibleState, PrimaryPeaks[T]{}
 			} else if c > a {
 				// (25) impossible state
 				//
@@ -519,7 +765,7 @@ func peakDetectTriple0[T Number](a, b, c T) (error, PrimaryPeaks[T]) {
 				// In this case, 'a' is equal to 'b', and 'b' is equal to 'c'.
 				// However, 'c' is also said to be greater than 'a', which is a contradiction.
 				// Therefore, this is an impossible state.
-				return errors.New("impossible state"), PrimaryPeaks[T]{}
+				return ErrImpossibleState, PrimaryPeaks[T]{}
 			} else /* c == a */ {
 				// (26) Peaks: {}
 				//
@@ -533,41 +779,3 @@ func peakDetectTriple0[T Number](a, b, c T) (error, PrimaryPeaks[T]) {
 		}
 	}
 }
-
-// We continue through all equal samples, terminating only upon encountering a sample
-// that is either greater, or less. We return 'false' if we find a greater sample, we
-// return 'true' if we find a smaller sample, to the right of the sample at index 'at'.
-func peakExtendsRight[T Number](at int, samples []T, initialSampleIsAPeak bool) bool {
-	for i := at; i < len(samples); i++ {
-		if i+1 < len(samples)-1 {
-			if samples[i] > samples[i+1] {
-				return true
-			} else if samples[i] < samples[i+1] {
-				return false
-			}
-		}
-	}
-	// If all samples to the right are equal, and we ran out of samples,
-	// whether the peak extends to the right will depend on if the first
-	// sample was a peak or not.
-	return initialSampleIsAPeak
-}
-
-// We continue through all equal samples, terminating only upon encountering a sample
-// that is either greater, or less. We return 'false' if we find a greater sample, we
-// return 'true' if we find a smaller sample, to the left of the sample at index 'at'.
-func peakExtendsLeft[T Number](at int, samples []T, initialSampleIsAPeak bool) bool {
-	for i := at; i >= 0; i-- {
-		if i-1 >= 0 {
-			if samples[i] > samples[i-1] {
-				return true
-			} else if samples[i] < samples[i-1] {
-				return false
-			}
-		}
-	}
-	// If all samples to the left are equal, and we ran out of samples,
-	// whether the peak extends to the left will depend on if the first
-	// sample was a peak or not.
-	return initialSampleIsAPeak
-}
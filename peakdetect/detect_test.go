@@ -0,0 +1,439 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import (
+	"errors"
+	"fmt"
+	"github.com/mowshon/iterium"
+	"math"
+	"math/bits"
+	"slices"
+	"strings"
+	"testing"
+)
+
+/*
+Exhaustive test of all possible peaks in a cluster of 3 samples, wherein
+each sample can only be one of 3 possible unique values.
+
+We have a total of three samples, each of which can be an arbitrary integer.
+
+Let us look at the problem by first limiting each sample to a set of
+the following possible unique values: {0, 1, 2}
+
+Because we have a total of 3 samples, and each of which can only take on
+the above possible values, the resulting space of possibilities is 3^3=27.
+
+In other words, there are 27 ways in which we can express 3 samples, such
+that we can encode all their possible differences. For example, the first
+sample being greater than the second or less than it, or equal to it. The
+same of the second and third samples, and the third and first.
+
+Suppose for example, that our alphabet was limited only to the symbols: {0, 1}
+
+In this case, we would not be able to express all possible differences in
+the cluster of 3 samples, because we could never express a full ramp.
+In order to be able to express a full ramp, we must have as many unique
+symbols in our alphabet, as there are samples.
+
+Hence, since we have 3 samples, our alphabet needs 3 unique symbols: {0, 1, 2}
+
+We can now express a full ramp:
+
+	                     |2|
+		           |1|1|
+	   |0|1|2|       |0|0|0|
+
+Next, we need to enumerate all possible differences between every two
+samples, out of the 3 total. This is the standard 'N choose K' problem,
+wherein we have to choose 2 values out of 3, without regard for order
+of the 2 chosen items.
+
+Out of a set of 3 samples, 'a', 'b' and 'c', we have the following 3 possibilities:
+
+				a b c       Let us encode our difference relations as such:
+				-----
+				a b         The choices 'ab', 'bc' and 'cd', allow
+				  b c       for the following possibilities:
+				a   c
+	                            (A > b)  Ab       (B > c)  Bc      (C > a)  Ca
+			                    (a < B)  aB       (b < C)  bC      (c < A)  aC
+			                    (a == b) ab       (b == c) bc      (c == a) ac
+
+We can now see what the logic would look like, when trying to express the above
+state space of 27 possible differences between two 2 samples, out of 3 total:
+
+if a < b { // aB
+
+	  if b < c { // bC
+	    if c < a { // cA
+	      <leaf>
+	    } else if c > a { // Ca
+	      <leaf>
+	    } else { // c == a, ca
+	      <leaf>
+	    }
+	  } else if b > c { Bc
+	      <branch>
+	  } else { // b == c, bc
+	      <branch>
+	  }
+	} else if a > b { // Ab
+
+	  <branch>
+	} else { // a == b, ab
+
+	  <branch>
+	}
+
+Below, we feed all 27 possible clusters of 3 samples, wherein the alphabet
+is limited to 3 unique symbols, leading to 3^3=27 total possibilities.
+*/
+func TestPeakDetectTriple(t *testing.T) {
+	tests := []struct {
+		a, b, c int
+		peaks   []int
+	}{
+		{1, 1, 1, []int{}},  /*      (0)  |1|1|1|            [2]            [3]    */
+		{1, 1, 2, []int{2}}, /*                     (1)  |1|1|1|            |2|    */
+		{1, 1, 3, []int{2}}, /*                                    (2)  |1|1|1|    */
+
+		{1, 2, 1, []int{1}},    /*   (3)    [2]            [2|2]            [3]    */
+		{1, 2, 2, []int{1, 2}}, /*        |1|1|1|   (4)  |1|1|1|          |2|2|    */
+		{1, 2, 3, []int{2}},    /*                                 (5)  |1|1|1|    */
+
+		{1, 3, 1, []int{1}},    /*   (6)    [3]            [3]            [3|3]    */
+		{1, 3, 2, []int{1}},    /*          |2|     (7)    |2|2|          |2|2|    */
+		{1, 3, 3, []int{1, 2}}, /*        |1|1|1|        |1|1|1|   (8)  |1|1|1|    */
+
+		{2, 1, 1, []int{0}},    /*   (9)  [2]                               [3]   */
+		{2, 1, 2, []int{0, 2}}, /*        |1|1|1|   (10) [2] [2]        [2] |2|   */
+		{2, 1, 3, []int{0, 2}}, /*                       |1|1|1|   (11) |1|1|1|   */
+
+		{2, 2, 1, []int{0, 1}}, /*   (12) [2|2]                             [3]   */
+		{2, 2, 2, []int{}},     /*        |1|1|1|   (13) |2|2|2|        |2|2|2|   */
+		{2, 2, 3, []int{2}},    /*                       |1|1|1|   (14) |1|1|1|   */
+
+		{2, 3, 1, []int{1}},    /*   (15)   [3]            [3]            [3|3]   */
+		{2, 3, 2, []int{1}},    /*        |2|2|     (16) |2|2|2|        |2|2|2|   */
+		{2, 3, 3, []int{1, 2}}, /*        |1|1|1|        |1|1|1|   (17) |1|1|1|   */
+
+		{3, 1, 1, []int{0}},    /*   (18) [3]            [3]            [3] [3]   */
+		{3, 1, 2, []int{0, 2}}, /*        |2|       (19) |2| [2]        |2| |2|   */
+		{3, 1, 3, []int{0, 2}}, /*        |1|1|1|        |1|1|1|   (20) |1|1|1|   */
+
+		{3, 2, 1, []int{0}},    /*   (21) [3]            [3]            [3] [3]   */
+		{3, 2, 2, []int{0}},    /*        |2|2|     (22) |2|2|2|        |2|2|2|   */
+		{3, 2, 3, []int{0, 2}}, /*        |1|1|1|        |1|1|1|   (23) |1|1|1|   */
+
+		{3, 3, 1, []int{0, 1}}, /*   (24) [3|3]          [3|3]          |3|3|3|   */
+		{3, 3, 2, []int{0, 1}}, /*        |2|2|     (25) |2|2|2|        |2|2|2|   */
+		{3, 3, 3, []int{}},     /*        |1|1|1|        |1|1|1|   (26) |1|1|1|   */
+	}
+	for _, test := range tests {
+		peaks, err := peakDetectTriple(test.a, test.b, test.c)
+		if err != nil {
+			t.Errorf("peakDetectTriple(%d, %d, %d): unexpected error: %v", test.a, test.b, test.c, err)
+		} else if !slices.Equal(test.peaks, peaks.peaks) {
+			t.Errorf("peakDetectTriple(%d, %d, %d): expected %v, got %v", test.a, test.b, test.c, test.peaks, peaks.peaks)
+		}
+	}
+}
+
+func TestPeakDetectTripleImpossibleState(t *testing.T) {
+	nan := math.NaN()
+	tests := [][3]float64{
+		{1, 2, nan},
+		{1, nan, 2},
+		{2, 1, nan},
+		{nan, 1, 2},
+	}
+	for _, test := range tests {
+		if _, err := peakDetectTriple(test[0], test[1], test[2]); !errors.Is(err, ErrImpossibleState) {
+			t.Errorf("peakDetectTriple(%v): expected %v, got %v", test, ErrImpossibleState, err)
+		}
+	}
+}
+
+func TestCountDecimal(t *testing.T) {
+	// Counts in this fixed radix system.
+	//
+	// |3|3|3|3|3|3|3|3|
+	// |2|2|2|2|2|2|2|2|
+	// |1|1|1|1|1|1|1|1|
+	// |0|0|0|0|0|0|0|0|
+	//
+	// In this case there are 8 places, each of which
+	// can be in only one of 4 possible values, leading
+	// to the total number of possibilities being
+	// 4^8=65536
+	//
+	// For example, one input may be something like:
+	//
+	// |0|1|2|3|4|3|2|1|   with the only peak located at index: 4
+	//
+	// or this input
+	//
+	// |0|3|3|2|1|1|2|1|   with peaks at 1, 2 and 6
+	//
+
+	//
+	// If we run it with the alphabet being: {0, 1, 2, 3, 4, 5, 6, 7}, then given
+	// 8 places as above, we would have a total of: 8^8=16777216 possibilities.
+	//
+	// I ran it, and it produced the correct output, but took about a minute or so
+	// to run. Therefore, I left it at a much faster, but still representative test,
+	// using only 4 unique symbols instead of 8.
+	//
+	// p := iterium.Product([]int{0, 1, 2, 3, 4, 5, 6, 7}, numberOfPlaces)
+	//
+	// When I ran it with the above, this was the output (trailing part of it):
+	//
+	// [7 7 7 7 7 7 7 1]
+	// [0 1 2 3 4 5 6]
+	// [7 7 7 7 7 7 7 2]
+	// [0 1 2 3 4 5 6]
+	// ...
+	// [7 7 7 7 7 7 7 6]
+	// [0 1 2 3 4 5 6]
+	// [7 7 7 7 7 7 7 7]
+	// []
+	// Total: 16777216
+	//
+
+	maxNumberOfPlaces := 8
+	for numberOfPlaces := 1; numberOfPlaces <= maxNumberOfPlaces; numberOfPlaces++ {
+		t.Run(fmt.Sprintf("places=%d", numberOfPlaces), func(t *testing.T) {
+			for _, samples := range product(t, []int{0, 1, 2, 3}, numberOfPlaces) {
+				peaks := DetectPeaks(samples)
+				if !isValid[int](&peaks) {
+					t.Fatalf("invalid peaks %v in samples %v", peaks.peaks, samples)
+				}
+			}
+		})
+	}
+}
+
+func TestCountBinary(t *testing.T) {
+	// Counts in a binary space of the specified number of bits,
+	// wherein the generated peaks are also all binary. This was
+	// an early test, designed to thoroughly test the merge logic.
+	maxBitWidth := 16
+	for bitWidth := 1; bitWidth <= maxBitWidth; bitWidth++ {
+		t.Run(fmt.Sprintf("bits=%d", bitWidth), func(t *testing.T) {
+			maxCount := 1 << bitWidth
+			for i := 0; i < maxCount; i++ {
+				testEach(t, i, bitWidth)
+			}
+		})
+	}
+}
+
+func testEach(t *testing.T, value int, bitWidth int) {
+	samples := make([]int, bitWidth)
+	samplePeaks := []int{}
+	unsignedValue := uint(value)
+	allBitsSet := bits.OnesCount(unsignedValue) == bitWidth
+
+	for j := 0; j < bitWidth; j++ {
+		if value&(1<<j) == 0 {
+			samples[bitWidth-j-1] = 0
+		} else {
+			if !allBitsSet {
+				samplePeaks = append(samplePeaks, bitWidth-j-1)
+			}
+			samples[bitWidth-j-1] = 1
+		}
+	}
+
+	if !allBitsSet {
+		slices.Reverse(samplePeaks)
+	} else {
+		samplePeaks = []int{}
+	}
+
+	peaks := DetectPeaks(samples)
+	if !slices.Equal(samplePeaks, peaks.peaks) {
+		t.Fatalf("samples %v: expected %v, got %v", samples, samplePeaks, peaks.peaks)
+	}
+
+	// For binary peaks, there should be zero secondary peaks, as they should
+	// all merge into one contiguous series of non-peak samples.
+	secondaryPeaks := DetectPeaksInPrimary(peaks)
+	if secondaryPeaks.GetPeakCount() > 0 || len(secondaryPeaks.GetPrimaryPeaks()) > 0 {
+		t.Fatalf("samples %v: expected no secondary peaks, got %v", samples, secondaryPeaks.GetPrimaryPeaks())
+	}
+}
+
+func TestDetectPeaksE(t *testing.T) {
+	nan := math.NaN()
+	tests := []struct {
+		samples []float64
+		peaks   []int
+		err     error
+	}{
+		{[]float64{}, nil, nil},
+		{[]float64{1}, nil, nil},
+		{[]float64{1, 2}, []int{1}, nil},
+		{[]float64{1, 2, 1, 3, 3}, []int{1, 3, 4}, nil},
+		{[]float64{1, nan, 2}, nil, ErrNaNSample},
+		{[]float64{1, 2, 3, 4, nan, 2}, nil, ErrNaNSample},
+		// These would otherwise be taken for ordinary clusters.
+		{[]float64{1, nan, 1}, nil, ErrNaNSample},
+		{[]float64{1, 2, 1, nan}, nil, ErrNaNSample},
+		{[]float64{nan}, nil, ErrNaNSample},
+	}
+	for _, test := range tests {
+		peaks, err := DetectPeaksE(test.samples)
+		if !errors.Is(err, test.err) {
+			t.Errorf("DetectPeaksE(%v): expected error %v, got %v", test.samples, test.err, err)
+		} else if !slices.Equal(test.peaks, peaks.peaks) {
+			t.Errorf("DetectPeaksE(%v): expected %v, got %v", test.samples, test.peaks, peaks.peaks)
+		}
+	}
+}
+
+func TestDetectPeaksNaNAbove(t *testing.T) {
+	nan := math.NaN()
+	primary := CreatePeaksWith([]float64{1, nan, 0, 2, 0, 3, 0}, []int{1, 3, 5})
+	if _, err := DetectPeaksInPrimaryE(primary); !errors.Is(err, ErrNaNSample) {
+		t.Errorf("expected %v, got %v", ErrNaNSample, err)
+	}
+	secondary := CreateSecondaryPeaksWith(CreatePeaksWith([]float64{2, nan, 3}, []int{0, 1, 2}), []int{1, 3, 5}, []int{1, 3, 5})
+	if _, err := DetectPeaksInSecondaryE(secondary); !errors.Is(err, ErrNaNSample) || !strings.Contains(err.Error(), "sample 3") {
+		t.Errorf("expected %v at sample 3, got %v", ErrNaNSample, err)
+	}
+	if _, err := DetectPeaksParallelE([]float64{1, 2, 1, 2, 1, 2, 1, nan, 1, 2, 1}, 2); !errors.Is(err, ErrNaNSample) {
+		t.Errorf("expected %v, got %v", ErrNaNSample, err)
+	}
+}
+
+func TestDetectPeaksByClusters(t *testing.T) {
+	// DetectPeaks must find exactly the same peaks as the clusters it
+	// replaces, across all the ways in which the clusters can be merged.
+	for numberOfPlaces := 1; numberOfPlaces <= 8; numberOfPlaces++ {
+		t.Run(fmt.Sprintf("places=%d", numberOfPlaces), func(t *testing.T) {
+			for _, samples := range product(t, []int{0, 1, 2, 3}, numberOfPlaces) {
+				expected, err := detectPeaksByClusters(samples)
+				if err != nil {
+					t.Fatal(err)
+				}
+				peaks := DetectPeaks(samples)
+				if !slices.Equal(expected.peaks, peaks.peaks) {
+					t.Fatalf("samples %v: expected %v, got %v", samples, expected.peaks, peaks.peaks)
+				}
+			}
+		})
+	}
+}
+
+func TestDetectPeaksInPlace(t *testing.T) {
+	// The samples are followed by spare capacity, which
+	// neither the detection, nor the merge, may write to.
+	backing := []int{1, 3, 2, 2, 5, 4, 4, 4, 6, 1, 7, 7, -1, -1}
+	samples := backing[:12]
+	for _, peaks := range []PrimaryPeaks[int]{DetectPeaks(samples), DetectPeaksParallel(samples, 2)} {
+		if &peaks.samples[0] != &samples[0] {
+			t.Error("expected the peaks to refer to the samples")
+		}
+		if !slices.Equal(peaks.peaks, []int{1, 4, 8, 10, 11}) {
+			t.Errorf("expected [1 4 8 10 11], got %v", peaks.peaks)
+		}
+		peaks.samples = append(peaks.samples, 0)
+	}
+	if backing[12] != -1 || backing[13] != -1 {
+		t.Errorf("expected the spare capacity to be left as is, got %v", backing[12:])
+	}
+}
+
+// Since the samples are not copied, a change made to them afterwards is
+// seen through the peaks, unless they are cloned before they are passed in.
+func TestDetectPeaksSharesSamples(t *testing.T) {
+	samples := []int{1, 3, 2, 5, 4}
+	peaks := DetectPeaks(samples)
+	cloned := DetectPeaks(slices.Clone(samples))
+	samples[1] = 0
+	if expected := []int{1, 0, 2, 5, 4}; !slices.Equal(expected, peaks.GetSamples()) {
+		t.Errorf("expected the change to be seen, got %v", peaks.GetSamples())
+	}
+	if expected := []int{0, 0, 0, 5, 0}; !slices.Equal(expected, peaks.Inflate()) {
+		t.Errorf("expected %v, got %v", expected, peaks.Inflate())
+	}
+	if expected := []int{0, 3, 0, 5, 0}; !slices.Equal(expected, cloned.Inflate()) {
+		t.Errorf("expected the clone to be left as is, got %v", cloned.Inflate())
+	}
+}
+
+func TestInflateE(t *testing.T) {
+	peaks := DetectPeaks([]int{1, 3, 2, 5, 4})
+	if inflated, err := peaks.InflateE(); err != nil || !slices.Equal(inflated, []int{0, 3, 0, 5, 0}) {
+		t.Errorf("expected [0 3 0 5 0], got %v, %v", inflated, err)
+	}
+	if _, err := peaks.InflateWithCountE(-1, &peaks); !errors.Is(err, ErrInvalidSampleCount) {
+		t.Errorf("expected %v, got %v", ErrInvalidSampleCount, err)
+	}
+	if _, err := peaks.InflateWithCountE(3, &peaks); !errors.Is(err, ErrInvalidSampleCount) {
+		t.Errorf("expected %v, got %v", ErrInvalidSampleCount, err)
+	}
+	if err := peaks.InflateIntoE(make([]int, 1), &peaks); !errors.Is(err, ErrInvalidSampleCount) {
+		t.Errorf("expected %v, got %v", ErrInvalidSampleCount, err)
+	}
+}
+
+// Enumerates all the possible samples of the specified number of places,
+// wherein each place holds one of the symbols of the alphabet.
+func product(t *testing.T, alphabet []int, numberOfPlaces int) [][]int {
+	t.Helper()
+	p := iterium.Product(alphabet, numberOfPlaces)
+	s, err := p.Slice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// The original implementation of DetectPeaksE, which copies every cluster of
+// samples, and merges the copies. It is kept as the reference against which
+// the tests, and the benchmarks, compare DetectPeaksE.
+func detectPeaksByClusters[T Number](samples []T) (PrimaryPeaks[T], error) {
+	at := 0
+	stride := 3
+	left := PrimaryPeaks[T]{}
+
+	for i := 0; at+stride <= len(samples); i++ {
+		if at > 0 {
+			right, err := peakDetectTriple[T](samples[at], samples[at+1], samples[at+2])
+			if err != nil {
+				return PrimaryPeaks[T]{}, clusterError(at, err)
+			}
+			if left, err = merge[T](left, right); err != nil {
+				return PrimaryPeaks[T]{}, clusterError(at, err)
+			}
+		} else {
+			var err error
+			if left, err = peakDetectTriple[T](samples[i], samples[i+1], samples[i+2]); err != nil {
+				return PrimaryPeaks[T]{}, clusterError(at, err)
+			}
+		}
+		at += stride
+	}
+
+	var err error
+	if len(samples)-at == 1 {
+		left, err = merge[T](left, peakDetectSample[T](samples[at]))
+	} else if len(samples)-at == 2 {
+		var right PrimaryPeaks[T]
+		if right, err = peakDetectPair[T](samples[at], samples[at+1]); err == nil {
+			left, err = merge[T](left, right)
+		}
+	}
+	if err != nil {
+		return PrimaryPeaks[T]{}, clusterError(at, err)
+	}
+
+	left.level = 1
+	return left, nil
+}
@@ -0,0 +1,34 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import "errors"
+
+var (
+	// ErrImpossibleState is reported when the samples of a cluster relate
+	// to each other in a way that ordered values cannot. The detection
+	// rejects the NaN samples, the only ones which cannot be ordered, up
+	// front, with ErrNaNSample, therefore, it is not expected to be seen.
+	ErrImpossibleState = errors.New("impossible state")
+
+	// ErrUnexpectedState is reported when two clusters, neither of which
+	// has any samples, are being merged.
+	ErrUnexpectedState = errors.New("unexpected state")
+
+	// ErrInvalidSampleCount is reported when a number of samples, or the
+	// space provided for them, does not fit the peaks being operated on.
+	ErrInvalidSampleCount = errors.New("invalid sample count")
+
+	// ErrNaNSample is reported when a sample is NaN, and therefore cannot
+	// be ordered, unless a NaNPolicy says how to treat such samples.
+	ErrNaNSample = errors.New("NaN sample")
+
+	// ErrInvalidPeaks is reported when the peaks do not agree with the
+	// samples they were detected in. See ValidationError.
+	ErrInvalidPeaks = errors.New("invalid peaks")
+
+	// ErrInvalidTolerance is reported when a tolerance is negative, or NaN.
+	ErrInvalidTolerance = errors.New("invalid tolerance")
+)
@@ -0,0 +1,99 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import "slices"
+
+// Samples of any type can be ordered by a comparison function, such as
+// cmp.Compare, or (*big.Int).Cmp, e.g., samples which carry their timestamp,
+// and labels, along with their value. Rather than duplicating the truth table
+// of the 3 sample clusters, and the merge logic, for such samples, we rank
+// them, such that the samples that compare equal have the same rank, and a
+// sample that compares greater than another has a greater rank. We then
+// detect the peaks in the ranks, which relate to each other exactly the same
+// way the samples do, and therefore, have the very same peaks.
+//
+// The comparison must order the samples consistently, i.e., the same way
+// slices.SortFunc requires it to.
+
+// FuncPeaks are the peaks detected at one level of the hierarchy, in samples
+// of any type, which are ordered by a comparison function. The peaks are the
+// indices of the original samples, irrespective of the level.
+type FuncPeaks[E any] struct {
+	samples []E
+	peaks   []int
+	level   int
+}
+
+func (p *FuncPeaks[E]) GetSampleCount() int {
+	return len(p.samples)
+}
+
+func (p *FuncPeaks[E]) GetPeakCount() int {
+	return len(p.peaks)
+}
+
+func (p *FuncPeaks[E]) GetSamples() []E {
+	return p.samples
+}
+
+func (p *FuncPeaks[_]) GetPeaks() []int {
+	return p.peaks
+}
+
+// GetValues returns the samples that are peaks, along with whatever
+// they carry, in the same order as their indices, as returned by GetPeaks.
+func (p *FuncPeaks[E]) GetValues() []E {
+	values := make([]E, len(p.peaks))
+	for i, at := range p.peaks {
+		values[i] = p.samples[at]
+	}
+	return values
+}
+
+// GetLevel returns the level of the peak hierarchy, at which the peaks
+// were detected, the same way PrimaryPeaks.GetLevel does.
+func (p *FuncPeaks[_]) GetLevel() int {
+	return p.level
+}
+
+// DetectPeaksFunc detects the same peaks as DetectPeaks, in samples that are
+// ordered by the comparison function, rather than by their value.
+func DetectPeaksFunc[E any](samples []E, cmp func(a, b E) int) FuncPeaks[E] {
+	// The ranks are integers, therefore, they can always be ordered.
+	primary, _ := DetectPeaksE[int](rank[E](samples, cmp))
+	return FuncPeaks[E]{slices.Clip(samples), primary.peaks, primary.level}
+}
+
+// IteratePeakDetectFunc detects the same peaks as IteratePeakDetect, with the
+// same number of iterations, in samples that are ordered by the comparison
+// function, rather than by their value.
+func IteratePeakDetectFunc[E any](iterations uint, samples []E, cmp func(a, b E) int) (FuncPeaks[E], bool) {
+	secondary, ok, _ := IteratePeakDetectE[int](iterations, rank[E](samples, cmp))
+	if !ok {
+		return FuncPeaks[E]{}, false
+	}
+	return FuncPeaks[E]{slices.Clip(samples), secondary.primaryPeaks, secondary.level}, true
+}
+
+// Returns the rank of each of the samples, where the least samples are of
+// rank 0, and the samples that compare equal are of the same rank.
+func rank[E any](samples []E, cmp func(a, b E) int) []int {
+	order := make([]int, len(samples))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(i, j int) int {
+		return cmp(samples[i], samples[j])
+	})
+	ranks := make([]int, len(samples))
+	for i := 1; i < len(order); i++ {
+		ranks[order[i]] = ranks[order[i-1]]
+		if cmp(samples[order[i-1]], samples[order[i]]) != 0 {
+			ranks[order[i]]++
+		}
+	}
+	return ranks
+}
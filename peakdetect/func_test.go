@@ -0,0 +1,90 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import (
+	"cmp"
+	"math/big"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDetectPeaksFunc(t *testing.T) {
+	type sample struct {
+		Timestamp time.Time
+		Value     float64
+		Labels    map[string]string
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	values := []float64{1, 3, 3, 2, 5, 1, 4, 2}
+	samples := make([]sample, len(values))
+	for i, value := range values {
+		samples[i] = sample{start.Add(time.Duration(i) * time.Minute), value, map[string]string{"host": "a"}}
+	}
+	byValue := func(a, b sample) int {
+		return cmp.Compare(a.Value, b.Value)
+	}
+
+	primary := DetectPeaksFunc(samples, byValue)
+	if expected := []int{1, 2, 4, 6}; !slices.Equal(expected, primary.GetPeaks()) || primary.GetLevel() != 1 {
+		t.Errorf("expected %v at level 1, got %v at level %d", expected, primary.GetPeaks(), primary.GetLevel())
+	}
+	// The peaks carry their timestamps, which need not be looked up.
+	for i, peak := range primary.GetValues() {
+		if at := primary.GetPeaks()[i]; !peak.Timestamp.Equal(samples[at].Timestamp) {
+			t.Errorf("expected the timestamp of sample %d, got %v", at, peak.Timestamp)
+		}
+	}
+
+	secondary, ok := IteratePeakDetectFunc(2, samples, byValue)
+	if expected := []int{4}; !ok || !slices.Equal(expected, secondary.GetPeaks()) || secondary.GetLevel() != 2 {
+		t.Errorf("expected %v at level 2, got %v at level %d", expected, secondary.GetPeaks(), secondary.GetLevel())
+	}
+	if _, ok := IteratePeakDetectFunc(0, samples, byValue); ok {
+		t.Error("expected no iterations")
+	}
+}
+
+func TestDetectPeaksFuncBigInt(t *testing.T) {
+	// Beyond the range of any of the Number types.
+	huge := new(big.Int).Lsh(big.NewInt(1), 100)
+	samples := make([]*big.Int, 0)
+	for _, n := range []int64{0, 2, 1, 2, 2, 0} {
+		samples = append(samples, new(big.Int).Add(huge, big.NewInt(n)))
+	}
+	peaks := DetectPeaksFunc(samples, (*big.Int).Cmp)
+	if expected := []int{1, 3, 4}; !slices.Equal(expected, peaks.GetPeaks()) {
+		t.Errorf("expected %v, got %v", expected, peaks.GetPeaks())
+	}
+}
+
+// The peaks are the same as those detected in the values themselves, and
+// reversing the comparison detects the troughs.
+func TestDetectPeaksFuncEquivalence(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 200; n++ {
+		samples := make([]int, r.Intn(100))
+		for i := range samples {
+			samples[i] = r.Intn(5) * 1000
+		}
+		peaks := DetectPeaksFunc(samples, cmp.Compare[int])
+		if expected := DetectPeaks(samples); !slices.Equal(expected.GetPeaks(), peaks.GetPeaks()) {
+			t.Fatalf("%v: expected %v, got %v", samples, expected.GetPeaks(), peaks.GetPeaks())
+		}
+		troughs := DetectPeaksFunc(samples, func(a, b int) int { return cmp.Compare(b, a) })
+		if expected := DetectTroughs(samples); !slices.Equal(expected.GetPeaks(), troughs.GetPeaks()) {
+			t.Fatalf("%v: expected troughs %v, got %v", samples, expected.GetPeaks(), troughs.GetPeaks())
+		}
+		for iterations := uint(1); iterations <= 4; iterations++ {
+			peaks, _ := IteratePeakDetectFunc(iterations, samples, cmp.Compare[int])
+			expected, _ := IteratePeakDetect(iterations, samples)
+			if expected.GetLevel() != peaks.GetLevel() || !slices.Equal(expected.GetPrimaryPeaks(), peaks.GetPeaks()) {
+				t.Fatalf("%v: %d iterations: expected %v, got %v", samples, iterations, expected.GetPrimaryPeaks(), peaks.GetPeaks())
+			}
+		}
+	}
+}
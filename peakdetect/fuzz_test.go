@@ -0,0 +1,173 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import (
+	"encoding/binary"
+	"math"
+	"slices"
+	"testing"
+)
+
+// Seeds shared by the fuzz targets. Each byte is an int sample, while each
+// 8 bytes are a float sample, therefore, the seeds exercise both.
+var fuzzSeeds = [][]byte{
+	{},
+	{1},
+	{1, 2},
+	{2, 2, 2},
+	{1, 8, 3, 5, 7, 2, 3, 6, 9, 0, 1, 3, 12, 1, 4, 8, 2, 4, 1, 9},
+	{3, 3, 2, 2, 2, 3, 3, 2, 0, 1},
+	{3, 3, 3, 3, 3, 3, 0, 1, 0, 2},
+	{0, 1, 5, 2, 6, 8, 7, 1, 8},
+	{0xff, 0x80, 0x7f, 0x00, 0x7f, 0x80, 0xff, 0x00},
+}
+
+func FuzzDetectPeaks(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		ints := fuzzInts(data)
+		expectOracle[int](t, ints, DetectPeaks[int](ints).peaks)
+		expectParallel[int](t, ints)
+
+		floats := fuzzFloats(data)
+		expectOracle[float64](t, floats, DetectPeaks[float64](floats).peaks)
+		expectParallel[float64](t, floats)
+	})
+}
+
+func FuzzIteratePeakDetect(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		expectHierarchy[int](t, fuzzInts(data))
+		expectHierarchy[float64](t, fuzzFloats(data))
+	})
+}
+
+// Each byte is a sample, such that there are few distinct
+// values, and therefore plateaus are common.
+func fuzzInts(data []byte) []int {
+	samples := make([]int, len(data))
+	for i, b := range data {
+		samples[i] = int(int8(b))
+	}
+	return samples
+}
+
+// Each 8 bytes are a sample, taken to be the bits of a float, such that the
+// samples span the whole range of floats, including the infinities. The NaNs
+// are dropped, since they cannot be ordered.
+func fuzzFloats(data []byte) []float64 {
+	samples := make([]float64, 0, len(data)/8)
+	for ; len(data) >= 8; data = data[8:] {
+		sample := math.Float64frombits(binary.LittleEndian.Uint64(data))
+		if !math.IsNaN(sample) {
+			samples = append(samples, sample)
+		}
+	}
+	return samples
+}
+
+// Checks the peaks against those found by brute force, i.e., by looking at
+// each sample in turn, and at its nearest neighbors of a different value.
+func expectOracle[T Number](t *testing.T, samples []T, peaks []int) {
+	t.Helper()
+	expected := oracle[T](samples)
+	if !slices.Equal(expected, peaks) {
+		t.Fatalf("samples %v: expected peaks %v, got %v", samples, expected, peaks)
+	}
+}
+
+// Checks that the peaks found concurrently, as well as those found by the
+// clusters DetectPeaks replaces, are the same as those found by DetectPeaks.
+func expectParallel[T Number](t *testing.T, samples []T) {
+	t.Helper()
+	peaks := DetectPeaks[T](samples)
+	clusters, err := detectPeaksByClusters[T](samples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(peaks.peaks, clusters.peaks) {
+		t.Fatalf("samples %v: expected clusters to find %v, got %v", samples, peaks.peaks, clusters.peaks)
+	}
+	for _, workers := range []int{2, 3, 5} {
+		parallel := DetectPeaksParallel[T](samples, workers)
+		if !slices.Equal(peaks.peaks, parallel.peaks) {
+			t.Fatalf("samples %v, %d workers: expected %v, got %v", samples, workers, peaks.peaks, parallel.peaks)
+		}
+	}
+}
+
+func oracle[T Number](samples []T) []int {
+	peaks := make([]int, 0)
+	for i := range samples {
+		left := i
+		for left >= 0 && samples[left] == samples[i] {
+			left--
+		}
+		right := i
+		for right < len(samples) && samples[right] == samples[i] {
+			right++
+		}
+		if left < 0 && right == len(samples) {
+			continue
+		}
+		if (left < 0 || samples[left] < samples[i]) && (right == len(samples) || samples[right] < samples[i]) {
+			peaks = append(peaks, i)
+		}
+	}
+	return peaks
+}
+
+// Walks the hierarchy, checking that the samples of each level are those of
+// the peaks of the level below, that the peaks of each level are those found
+// by brute force in its samples, and therefore, that the primary peaks of each
+// level are a subset of those of the level below. Since each level has fewer
+// samples than the one below, the walk must end before the samples run out.
+func expectHierarchy[T Number](t *testing.T, samples []T) {
+	t.Helper()
+	primary, err := DetectPeaksE[T](samples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectOracle[T](t, samples, primary.peaks)
+
+	below := primary.peaks
+	secondary, err := DetectPeaksInPrimaryE[T](primary)
+	for level := 2; ; level++ {
+		if err != nil {
+			t.Fatalf("level %d: %v", level, err)
+		}
+		if level > len(samples)+2 {
+			t.Fatalf("samples %v: level %d exceeds the number of samples", samples, level)
+		}
+		if secondary.level != level {
+			t.Fatalf("samples %v: expected level %d, got %d", samples, level, secondary.level)
+		}
+		expected := make([]T, len(below))
+		for i, at := range below {
+			expected[i] = samples[at]
+		}
+		if !slices.Equal(expected, secondary.samples) {
+			t.Fatalf("samples %v, level %d: expected samples %v, got %v", samples, level, expected, secondary.samples)
+		}
+		expectOracle[T](t, secondary.samples, secondary.peaks)
+		for i, at := range secondary.peaks {
+			if secondary.primaryPeaks[i] != below[at] {
+				t.Fatalf("samples %v, level %d: peak %d is at %d, rather than at %d",
+					samples, level, at, secondary.primaryPeaks[i], below[at])
+			}
+		}
+		if secondary.GetPeakCount() == 0 {
+			break
+		}
+		below = secondary.primaryPeaks
+		secondary, err = DetectPeaksInSecondaryE[T](secondary)
+	}
+}
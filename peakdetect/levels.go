@@ -0,0 +1,29 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+// Returns the indices within the samples of the peaks at each level, from
+// the primary level, up to, and including, the specified level, or the first
+// level without any peaks, if that comes first, i.e., the same levels as
+// those IteratePeakDetectE goes through, except for the secondary level,
+// which it detects even if the primary level has no peaks.
+func detectLevels[T Number](levels uint, samples []T) ([][]int, error) {
+	primary, err := DetectPeaksE[T](samples)
+	if err != nil {
+		return nil, err
+	}
+	peaks := [][]int{primary.peaks}
+	if levels < 2 || primary.GetPeakCount() == 0 {
+		return peaks, nil
+	}
+	secondary, err := DetectPeaksInPrimaryE[T](primary)
+	for ; err == nil; secondary, err = DetectPeaksInSecondaryE[T](secondary) {
+		peaks = append(peaks, secondary.primaryPeaks)
+		if uint(secondary.level) >= levels || secondary.GetPeakCount() == 0 {
+			return peaks, nil
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,133 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+)
+
+// ManyResult is the result of IteratePeakDetect for one of many series,
+// named the same as the series. If the detection failed, or never ran,
+// due to the context being done, then Err says why.
+type ManyResult[T Number] struct {
+	Name  string
+	Peaks SecondaryPeaks[T]
+	Err   error
+}
+
+// DetectMany runs IteratePeakDetect with the specified number of iterations
+// for each of the series, using up to the specified number of workers, and
+// returns the result of each of them by its name. If the number of workers is
+// not positive, then as many workers are used as there are CPUs.
+//
+// Once the context is done, no more series are detected, the series that are
+// being detected are interrupted, see IteratePeakDetectContext, and all those
+// that were not detected are given the error of the context, which is also
+// returned.
+func DetectMany[T Number](ctx context.Context, iterations uint, series map[string][]T, workers int) (map[string]ManyResult[T], error) {
+	names := make([]string, 0, len(series))
+	for name := range series {
+		names = append(names, name)
+	}
+	results := make(map[string]ManyResult[T], len(series))
+	err := DetectManyFrom[T](ctx, iterations, func() (string, []T, bool) {
+		if len(names) == 0 {
+			return "", nil, false
+		}
+		name := names[0]
+		names = names[1:]
+		return name, series[name], true
+	}, workers, func(r ManyResult[T]) {
+		results[r.Name] = r
+	})
+	if err != nil {
+		for name := range series {
+			if _, ok := results[name]; !ok {
+				results[name] = ManyResult[T]{Name: name, Err: err}
+			}
+		}
+	}
+	return results, err
+}
+
+// DetectManyFrom is DetectMany for series that are not all at hand at once.
+// The series are taken from 'next', one at a time, until it returns false,
+// and the result of each of them is passed to 'result', as soon as it is
+// ready. Neither function is ever called concurrently with itself, and all
+// the results are passed before DetectManyFrom returns.
+//
+// Once the context is done, no more series are taken from 'next', and the
+// series that were taken, but not detected, are given the error of the
+// context, which is also returned.
+func DetectManyFrom[T Number](ctx context.Context, iterations uint, next func() (string, []T, bool), workers int, result func(ManyResult[T])) error {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	type job struct {
+		name    string
+		samples []T
+	}
+	jobs := make(chan job)
+	results := make(chan ManyResult[T])
+
+	// Whether the series ran out before the context was done. It is written
+	// before the jobs are closed, and read after all the results are in.
+	var interrupted bool
+	go func() {
+		defer close(jobs)
+		for {
+			if ctx.Err() != nil {
+				interrupted = true
+				return
+			}
+			name, samples, ok := next()
+			if !ok {
+				return
+			}
+			select {
+			case jobs <- job{name, samples}:
+			case <-ctx.Done():
+				interrupted = true
+				results <- ManyResult[T]{Name: name, Err: ctx.Err()}
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				r := ManyResult[T]{Name: j.name}
+				r.Peaks, _, r.Err = IteratePeakDetectContext[T](ctx, iterations, j.samples, nil)
+				results <- r
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// Whether a series that was taken was interrupted, which can happen after
+	// the series ran out, if the context is done while the last of them are
+	// being detected.
+	var canceled bool
+	for r := range results {
+		if err := ctx.Err(); err != nil && errors.Is(r.Err, err) {
+			canceled = true
+		}
+		result(r)
+	}
+	if interrupted || canceled {
+		return ctx.Err()
+	}
+	return nil
+}
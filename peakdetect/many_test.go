@@ -0,0 +1,162 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestDetectMany(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	series := make(map[string][]float64)
+	for i := 0; i < 100; i++ {
+		samples := make([]float64, r.Intn(1000))
+		for j := range samples {
+			samples[j] = float64(r.Intn(100))
+		}
+		series[fmt.Sprintf("host-%d", i)] = samples
+	}
+	series["nan"] = []float64{1, 2, math.NaN()}
+
+	for _, workers := range []int{0, 1, 4} {
+		t.Run(fmt.Sprintf("%d workers", workers), func(t *testing.T) {
+			results, err := DetectMany(context.Background(), 3, series, workers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != len(series) {
+				t.Fatalf("expected %d results, got %d", len(series), len(results))
+			}
+			for name, samples := range series {
+				result := results[name]
+				expected, _, err := IteratePeakDetectE(3, samples)
+				if result.Name != name || (result.Err == nil) != (err == nil) {
+					t.Errorf("%s: expected error %v, got %v, for %q", name, err, result.Err, result.Name)
+				}
+				if !slices.Equal(expected.GetPrimaryPeaks(), result.Peaks.GetPrimaryPeaks()) {
+					t.Errorf("%s: expected %v, got %v", name, expected.GetPrimaryPeaks(), result.Peaks.GetPrimaryPeaks())
+				}
+			}
+			if !errors.Is(results["nan"].Err, ErrNaNSample) {
+				t.Errorf("expected %v, got %v", ErrNaNSample, results["nan"].Err)
+			}
+		})
+	}
+}
+
+func TestDetectManyCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	series := map[string][]int{"a": {1, 3, 2}, "b": {2, 1, 2}}
+	results, err := DetectMany(ctx, 2, series, 2)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	for name := range series {
+		if result, ok := results[name]; !ok || !errors.Is(result.Err, context.Canceled) {
+			t.Errorf("%s: expected %v, got %+v", name, context.Canceled, result)
+		}
+	}
+}
+
+// The series stop being taken once the context is done, and every series
+// that was taken has a result.
+func TestDetectManyFromCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	taken := 0
+	next := func() (string, []int, bool) {
+		taken++
+		if taken == 10 {
+			cancel()
+		}
+		return fmt.Sprint(taken), []int{1, 3, 2, 5, 1}, true
+	}
+	var names []string
+	err := DetectManyFrom(ctx, 2, next, 3, func(r ManyResult[int]) {
+		names = append(names, r.Name)
+		if r.Err == nil && !slices.Equal([]int{3}, r.Peaks.GetPrimaryPeaks()) {
+			t.Errorf("%s: expected [3], got %v", r.Name, r.Peaks.GetPrimaryPeaks())
+		} else if r.Err != nil && !errors.Is(r.Err, context.Canceled) {
+			t.Errorf("%s: expected %v, got %v", r.Name, context.Canceled, r.Err)
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected %v, got %v", context.Canceled, err)
+	}
+	if len(names) != taken {
+		t.Errorf("expected a result for each of the %d series taken, got %d", taken, len(names))
+	}
+}
+
+// The context being done while the last series is detected, after the series
+// ran out, is reported the same as it is before they run out. The series is
+// long enough for the detection to be interrupted, rather than to finish first.
+func TestDetectManyFromCanceledLast(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	samples := make([]int, 2_000_000)
+	for i := range samples {
+		samples[i] = r.Intn(1000)
+	}
+	for run := 0; run < 5; run++ {
+		ctx, cancel := context.WithCancel(context.Background())
+		taken := false
+		next := func() (string, []int, bool) {
+			if taken {
+				// The series has been handed to the worker.
+				cancel()
+				return "", nil, false
+			}
+			taken = true
+			return "a", samples, true
+		}
+		var interrupted bool
+		err := DetectManyFrom(ctx, math.MaxUint, next, 1, func(r ManyResult[int]) {
+			interrupted = errors.Is(r.Err, context.Canceled)
+		})
+		if interrupted && !errors.Is(err, context.Canceled) {
+			t.Fatalf("%d: expected %v, got %v", run, context.Canceled, err)
+		} else if !interrupted && err != nil {
+			t.Fatalf("%d: expected no error, got %v", run, err)
+		}
+		cancel()
+	}
+}
+
+func TestDetectManyFrom(t *testing.T) {
+	series := [][]int{{1, 3, 2}, {1, 3, 2, 5, 1}, {}, {2, 2}}
+	i := 0
+	next := func() (string, []int, bool) {
+		if i == len(series) {
+			return "", nil, false
+		}
+		i++
+		return fmt.Sprint(i - 1), series[i-1], true
+	}
+	results := make(map[string][]int)
+	if err := DetectManyFrom(context.Background(), 2, next, 2, func(r ManyResult[int]) {
+		if r.Err != nil {
+			t.Errorf("%s: %v", r.Name, r.Err)
+		}
+		results[r.Name] = r.Peaks.GetPrimaryPeaks()
+	}); err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]int{"0": nil, "1": {3}, "2": nil, "3": nil}
+	if len(results) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, results)
+	}
+	for name, peaks := range expected {
+		if !slices.Equal(peaks, results[name]) {
+			t.Errorf("%s: expected %v, got %v", name, peaks, results[name])
+		}
+	}
+}
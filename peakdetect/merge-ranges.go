@@ -0,0 +1,105 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+// Merges two adjacent ranges of the samples, the 'left' one from 'leftStart'
+// to 'mid', and the 'right' one from 'mid' to 'rightEnd', exclusive, given
+// the indices of their peaks, and returns the indices of the peaks of the
+// merged range. The 'left' peaks are appended to, and therefore overwritten.
+//
+// This is the same merge as that of merge, case for case, see there for the
+// reasoning behind each case, except that rather than copy the samples, and
+// the peaks, of both sides, we only fix up the peaks on the merge boundary.
+func mergeRanges[T Number](samples []T, left []int, leftStart, mid int, right []int, rightEnd int) ([]int, error) {
+	if leftStart < mid && mid == rightEnd {
+		return left, nil
+	} else if leftStart == mid && mid < rightEnd {
+		return right, nil
+	} else if leftStart == mid && mid == rightEnd {
+		return nil, ErrUnexpectedState
+	}
+	leftSample := samples[mid-1]
+	rightSample := samples[mid]
+	isLastSamplePeak := len(left) > 0 && left[len(left)-1] == mid-1
+	isFirstSamplePeak := len(right) > 0 && right[0] == mid
+
+	// A side without any peaks is a side whose samples are all equal, and
+	// therefore, if any of them becomes a peak, then all of them do.
+	addLeft := false
+	addRight := false
+	if isLastSamplePeak {
+		if isFirstSamplePeak {
+			if leftSample > rightSample {
+				right = removeLeadingPeaks[T](samples, right, rightSample)
+			} else if leftSample < rightSample {
+				left = removeTrailingPeaks[T](samples, left, leftSample)
+			}
+		} else if leftSample == rightSample {
+			if len(right) == 0 {
+				addRight = true
+			} else {
+				left = removeTrailingPeaks[T](samples, left, leftSample)
+			}
+		} else if leftSample < rightSample {
+			left = removeTrailingPeaks[T](samples, left, leftSample)
+			addRight = len(right) == 0
+		}
+	} else {
+		if !isFirstSamplePeak {
+			if leftSample > rightSample {
+				addLeft = len(left) == 0
+			} else if leftSample < rightSample {
+				addRight = len(right) == 0
+			}
+		} else if leftSample == rightSample {
+			if len(left) == 0 {
+				addLeft = true
+			} else {
+				right = removeLeadingPeaks[T](samples, right, rightSample)
+			}
+		} else if leftSample > rightSample {
+			addLeft = len(left) == 0
+			right = removeLeadingPeaks[T](samples, right, rightSample)
+		}
+	}
+
+	if addLeft {
+		left = appendRange(left, leftStart, mid)
+	}
+	peaks := append(left, right...)
+	if addRight {
+		peaks = appendRange(peaks, mid, rightEnd)
+	}
+	return peaks, nil
+}
+
+func appendRange(peaks []int, start, end int) []int {
+	for at := start; at < end; at++ {
+		peaks = append(peaks, at)
+	}
+	return peaks
+}
+
+// invariant: the last sample of the 'left' range must be a peak
+//
+// Removes the trailing peaks that are contiguous, and equal to the 'sampleValue'.
+func removeTrailingPeaks[T Number](samples []T, left []int, sampleValue T) []int {
+	last := len(left) - 1
+	for last > 0 && samples[left[last-1]] == sampleValue && left[last]-left[last-1] == 1 {
+		last--
+	}
+	return left[:last]
+}
+
+// invariant: the first sample of the 'right' range must be a peak
+//
+// Removes the leading peaks that are contiguous, and equal to the 'sampleValue'.
+func removeLeadingPeaks[T Number](samples []T, right []int, sampleValue T) []int {
+	first := 0
+	for first < len(right)-1 && samples[right[first+1]] == sampleValue && right[first+1]-right[first] == 1 {
+		first++
+	}
+	return right[first+1:]
+}
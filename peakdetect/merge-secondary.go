@@ -4,15 +4,15 @@
 
 package peakdetect
 
-import "log"
+import "slices"
 
-func mergeSecondary[T Number](left, right SecondaryPeaks[T]) SecondaryPeaks[T] {
+func mergeSecondary[T Number](left, right SecondaryPeaks[T]) (SecondaryPeaks[T], error) {
 	if left.samples != nil && right.samples == nil {
-		return left
+		return left, nil
 	} else if left.samples == nil && right.samples != nil {
-		return right
+		return right, nil
 	} else if left.samples == nil && right.samples == nil {
-		log.Fatal("unexpected state")
+		return SecondaryPeaks[T]{}, ErrUnexpectedState
 	}
 	leftSample := left.getLastSample()
 	rightSample := right.getFirstSample()
@@ -27,7 +27,7 @@ func mergeSecondary[T Number](left, right SecondaryPeaks[T]) SecondaryPeaks[T] {
 				// nor right. Therefore, we simply merge the two sample
 				// arrays into one, and then merge the peaks, while fixing
 				// up the offsets.
-				return mergeSamples0[T](left, right)
+				return mergeSamples0[T](left, right), nil
 			} else {
 				// In this case, the last sample of the 'left' peaks cluster,
 				// as well as the first sample of the 'right' peaks cluster
@@ -40,13 +40,13 @@ func mergeSecondary[T Number](left, right SecondaryPeaks[T]) SecondaryPeaks[T] {
 					// peaks cluster. We want to go through the 'right' side, and remove
 					// the peak property from all samples that are equal to the first
 					// sample, and are contiguous with it.
-					return mergeSamples0[T](left, removeContiguousPeaksFromRightSide0[T](right, rightSample))
+					return mergeSamples0[T](left, removeContiguousPeaksFromRightSide0[T](right, rightSample)), nil
 				} else {
 					// The right sample is greater, therefore the peak is in the
 					// 'right' peaks cluster. We want to go through the 'left' side,
 					// and remove the peak property from all samples that are equal
 					// to the last sample, and are contiguous with it.
-					return mergeSamples0[T](removeContiguousPeaksFromLeftSide0[T](left, leftSample), right)
+					return mergeSamples0[T](removeContiguousPeaksFromLeftSide0[T](left, leftSample), right), nil
 				}
 			}
 		} else {
@@ -83,9 +83,9 @@ func mergeSecondary[T Number](left, right SecondaryPeaks[T]) SecondaryPeaks[T] {
 			// on the 'right' side.
 			if leftSample == rightSample {
 				if len(right.peaks) == 0 {
-					return mergeSamples0[T](left, addContiguousPeaksToRightSide0[T](right))
+					return mergeSamples0[T](left, addContiguousPeaksToRightSide0[T](right)), nil
 				} else {
-					return mergeSamples0[T](removeContiguousPeaksFromLeftSide0[T](left, leftSample), right)
+					return mergeSamples0[T](removeContiguousPeaksFromLeftSide0[T](left, leftSample), right), nil
 				}
 			} else {
 				// The last sample of the 'left' peaks cluster is a peak,
@@ -108,7 +108,7 @@ func mergeSecondary[T Number](left, right SecondaryPeaks[T]) SecondaryPeaks[T] {
 					// 'left' peaks cluster. We want to go through the 'right' side,
 					// and remove the peak property from all samples that are contiguous
 					// to the 'rightSample', and are equal to it.
-					return mergeSamples0[T](left, right)
+					return mergeSamples0[T](left, right), nil
 				} else {
 					// The 'right' sample is greater, but it is the 'left' sample that is a peak.
 					// Therefore, we must remove the peak property from all contiguous trailing
@@ -121,7 +121,7 @@ func mergeSecondary[T Number](left, right SecondaryPeaks[T]) SecondaryPeaks[T] {
 					// a peak in the 'right' peaks cluster has already been determined when
 					// we computed the peaks in the right peaks cluster.
 					return mergeSamples0[T](removeContiguousPeaksFromLeftSide0[T](left, leftSample),
-						addContiguousPeaksToRightSide0[T](right))
+						addContiguousPeaksToRightSide0[T](right)), nil
 				}
 			}
 		}
@@ -138,14 +138,14 @@ func mergeSecondary[T Number](left, right SecondaryPeaks[T]) SecondaryPeaks[T] {
 				// propagate neither 'left' nor 'right'. Because there are no peaks
 				// to propagate, we simply merge the two sample arrays into one,
 				// and then merge the peaks, and then fix up the peak offsets.
-				return mergeSamples0[T](left, right)
+				return mergeSamples0[T](left, right), nil
 			} else {
 				if leftSample > rightSample {
 					// In this case, the last sample of the 'left' peaks cluster is
 					// greater than the first sample of the 'right' peaks cluster.
 					//
 					// However, neither is a peak in its corresponding peak cluster.
-					return mergeSamples0[T](addContiguousPeaksToLeftSide0[T](left), right)
+					return mergeSamples0[T](addContiguousPeaksToLeftSide0[T](left), right), nil
 				} else {
 					// In this case, the last sample of the 'left' peaks cluster
 					// is less than the first sample of the 'right' peaks cluster.
@@ -157,7 +157,7 @@ func mergeSecondary[T Number](left, right SecondaryPeaks[T]) SecondaryPeaks[T] {
 					// We have to assign the peak property to the first sample of the
 					// 'right' peaks cluster, and then extend this property to the right
 					// for all samples that are equal to the first sample.
-					return mergeSamples0[T](left, addContiguousPeaksToRightSide0[T](right))
+					return mergeSamples0[T](left, addContiguousPeaksToRightSide0[T](right)), nil
 				}
 			}
 		} else {
@@ -172,9 +172,9 @@ func mergeSecondary[T Number](left, right SecondaryPeaks[T]) SecondaryPeaks[T] {
 			// sample on the right, since the two are equal in this case.
 			if leftSample == rightSample {
 				if len(left.peaks) == 0 {
-					return mergeSamples0[T](addContiguousPeaksToLeftSide0[T](left), right)
+					return mergeSamples0[T](addContiguousPeaksToLeftSide0[T](left), right), nil
 				} else {
-					return mergeSamples0[T](left, removeContiguousPeaksFromRightSide0[T](right, rightSample))
+					return mergeSamples0[T](left, removeContiguousPeaksFromRightSide0[T](right, rightSample)), nil
 				}
 			} else {
 				// Additionally, the first sample of the 'right' peaks cluster,
@@ -194,13 +194,13 @@ func mergeSecondary[T Number](left, right SecondaryPeaks[T]) SecondaryPeaks[T] {
 					// cluster. We want to go through the 'right' side, and remove the peak
 					// property from all samples that are contiguous to the first sample,
 					// and are equal to it.
-					return mergeSamples0[T](addContiguousPeaksToLeftSide0[T](left), removeContiguousPeaksFromRightSide0[T](right, rightSample))
+					return mergeSamples0[T](addContiguousPeaksToLeftSide0[T](left), removeContiguousPeaksFromRightSide0[T](right, rightSample)), nil
 				} else {
 					// The 'right' sample is greater than the 'left' sample, and is a peak.
 					//
 					// In this case, we simply merge the 'left' and the 'right' side without
 					// any additional changes to the peaks of either side.
-					return mergeSamples0[T](left, right)
+					return mergeSamples0[T](left, right), nil
 				}
 			}
 		}
@@ -239,8 +239,9 @@ func addContiguousPeaksToLeftSide0[T Number](left SecondaryPeaks[T]) SecondaryPe
 		// Pass the 'left.originalPeaks' as the new 'primaryPeaks', because we just
 		// synthetically created all the peak indices, and now we need to set the
 		// 'primaryPeaks' to the original peak indices, i.e., the peak indices in the
-		// primary array
-		return CreateSecondaryPeaksWith[T](CreatePeaksWith[T](left.samples, left.createAllPeaks()), left.originalPeaks, left.originalPeaks)
+		// primary array. The two must not share the same array, since both are later
+		// appended to when merged, and one would overwrite the other.
+		return CreateSecondaryPeaksWith[T](CreatePeaksWith[T](left.samples, left.createAllPeaks()), slices.Clone(left.originalPeaks), left.originalPeaks)
 	} else {
 		return left
 	}
@@ -282,8 +283,9 @@ func addContiguousPeaksToRightSide0[T Number](right SecondaryPeaks[T]) Secondary
 		// Pass the 'right.originalPeaks' as the new 'primaryPeaks', because we just
 		// synthetically created all the peak indices, and now we need to set the
 		// 'primaryPeaks' to the original peak indices, i.e., the peak indices in the
-		// primary array
-		return CreateSecondaryPeaksWith[T](CreatePeaksWith[T](right.samples, right.createAllPeaks()), right.originalPeaks, right.originalPeaks)
+		// primary array. The two must not share the same array, since both are later
+		// appended to when merged, and one would overwrite the other.
+		return CreateSecondaryPeaksWith[T](CreatePeaksWith[T](right.samples, right.createAllPeaks()), slices.Clone(right.originalPeaks), right.originalPeaks)
 	} else {
 		return right
 	}
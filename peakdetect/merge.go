@@ -4,17 +4,13 @@
 
 package peakdetect
 
-import (
-	"log"
-)
-
-func merge[T Number](left, right PrimaryPeaks[T]) PrimaryPeaks[T] {
+func merge[T Number](left, right PrimaryPeaks[T]) (PrimaryPeaks[T], error) {
 	if left.samples != nil && right.samples == nil {
-		return left
+		return left, nil
 	} else if left.samples == nil && right.samples != nil {
-		return right
+		return right, nil
 	} else if left.samples == nil && right.samples == nil {
-		log.Fatal("unexpected state")
+		return PrimaryPeaks[T]{}, ErrUnexpectedState
 	}
 	leftSample := left.getLastSample()
 	rightSample := right.getFirstSample()
@@ -29,7 +25,7 @@ func merge[T Number](left, right PrimaryPeaks[T]) PrimaryPeaks[T] {
 				// nor right. Therefore, we simply merge the two sample
 				// arrays into one, and then merge the peaks, while fixing
 				// up the offsets.
-				return mergeSamples[T](left, right)
+				return mergeSamples[T](left, right), nil
 			} else {
 				// In this case, the last sample of the 'left' peaks cluster,
 				// as well as the first sample of the 'right' peaks cluster
@@ -42,13 +38,13 @@ func merge[T Number](left, right PrimaryPeaks[T]) PrimaryPeaks[T] {
 					// peaks cluster. We want to go through the 'right' side, and remove
 					// the peak property from all samples that are equal to the first
 					// sample, and are contiguous with it.
-					return mergeSamples[T](left, removeContiguousPeaksFromRightSide[T](right, rightSample))
+					return mergeSamples[T](left, removeContiguousPeaksFromRightSide[T](right, rightSample)), nil
 				} else {
 					// The right sample is greater, therefore the peak is in the
 					// 'right' peaks cluster. We want to go through the 'left' side,
 					// and remove the peak property from all samples that are equal
 					// to the last sample, and are contiguous with it.
-					return mergeSamples[T](removeContiguousPeaksFromLeftSide[T](left, leftSample), right)
+					return mergeSamples[T](removeContiguousPeaksFromLeftSide[T](left, leftSample), right), nil
 				}
 			}
 		} else {
@@ -85,9 +81,9 @@ func merge[T Number](left, right PrimaryPeaks[T]) PrimaryPeaks[T] {
 			// on the 'right' side.
 			if leftSample == rightSample {
 				if len(right.peaks) == 0 {
-					return mergeSamples[T](left, addContiguousPeaksToRightSide[T](right))
+					return mergeSamples[T](left, addContiguousPeaksToRightSide[T](right)), nil
 				} else {
-					return mergeSamples[T](removeContiguousPeaksFromLeftSide[T](left, leftSample), right)
+					return mergeSamples[T](removeContiguousPeaksFromLeftSide[T](left, leftSample), right), nil
 				}
 			} else {
 				// The last sample of the 'left' peaks cluster is a peak,
@@ -110,7 +106,7 @@ func merge[T Number](left, right PrimaryPeaks[T]) PrimaryPeaks[T] {
 					// 'left' peaks cluster. We want to go through the 'right' side,
 					// and remove the peak property from all samples that are contiguous
 					// to the 'rightSample', and are equal to it.
-					return mergeSamples[T](left, right)
+					return mergeSamples[T](left, right), nil
 				} else {
 					// The 'right' sample is greater, but it is the 'left' sample that is a peak.
 					// Therefore, we must remove the peak property from all contiguous trailing
@@ -123,7 +119,7 @@ func merge[T Number](left, right PrimaryPeaks[T]) PrimaryPeaks[T] {
 					// a peak in the 'right' peaks cluster has already been determined when
 					// we computed the peaks in the right peaks cluster.
 					return mergeSamples[T](removeContiguousPeaksFromLeftSide[T](left, leftSample),
-						addContiguousPeaksToRightSide[T](right))
+						addContiguousPeaksToRightSide[T](right)), nil
 				}
 			}
 		}
@@ -140,14 +136,14 @@ func merge[T Number](left, right PrimaryPeaks[T]) PrimaryPeaks[T] {
 				// propagate neither 'left' nor 'right'. Because there are no peaks
 				// to propagate, we simply merge the two sample arrays into one,
 				// and then merge the peaks, and then fix up the peak offsets.
-				return mergeSamples[T](left, right)
+				return mergeSamples[T](left, right), nil
 			} else {
 				if leftSample > rightSample {
 					// In this case, the last sample of the 'left' peaks cluster is
 					// greater than the first sample of the 'right' peaks cluster.
 					//
 					// However, neither is a peak in its corresponding peak cluster.
-					return mergeSamples[T](addContiguousPeaksToLeftSide[T](left), right)
+					return mergeSamples[T](addContiguousPeaksToLeftSide[T](left), right), nil
 				} else {
 					// In this case, the last sample of the 'left' peaks cluster
 					// is less than the first sample of the 'right' peaks cluster.
@@ -159,7 +155,7 @@ func merge[T Number](left, right PrimaryPeaks[T]) PrimaryPeaks[T] {
 					// We have to assign the peak property to the first sample of the
 					// 'right' peaks cluster, and then extend this property to the right
 					// for all samples that are equal to the first sample.
-					return mergeSamples[T](left, addContiguousPeaksToRightSide[T](right))
+					return mergeSamples[T](left, addContiguousPeaksToRightSide[T](right)), nil
 				}
 			}
 		} else {
@@ -174,9 +170,9 @@ func merge[T Number](left, right PrimaryPeaks[T]) PrimaryPeaks[T] {
 			// sample on the right, since the two are equal in this case.
 			if leftSample == rightSample {
 				if len(left.peaks) == 0 {
-					return mergeSamples[T](addContiguousPeaksToLeftSide[T](left), right)
+					return mergeSamples[T](addContiguousPeaksToLeftSide[T](left), right), nil
 				} else {
-					return mergeSamples[T](left, removeContiguousPeaksFromRightSide[T](right, rightSample))
+					return mergeSamples[T](left, removeContiguousPeaksFromRightSide[T](right, rightSample)), nil
 				}
 			} else {
 				// Additionally, the first sample of the 'right' peaks cluster,
@@ -196,13 +192,13 @@ func merge[T Number](left, right PrimaryPeaks[T]) PrimaryPeaks[T] {
 					// cluster. We want to go through the 'right' side, and remove the peak
 					// property from all samples that are contiguous to the first sample,
 					// and are equal to it.
-					return mergeSamples[T](addContiguousPeaksToLeftSide[T](left), removeContiguousPeaksFromRightSide[T](right, rightSample))
+					return mergeSamples[T](addContiguousPeaksToLeftSide[T](left), removeContiguousPeaksFromRightSide[T](right, rightSample)), nil
 				} else {
 					// The 'right' sample is greater than the 'left' sample, and is a peak.
 					//
 					// In this case, we simply merge the 'left' and the 'right' side without
 					// any additional changes to the peaks of either side.
-					return mergeSamples[T](left, right)
+					return mergeSamples[T](left, right), nil
 				}
 			}
 		}
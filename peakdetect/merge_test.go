@@ -0,0 +1,60 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestMergeOfTriples(t *testing.T) {
+	tests := []struct {
+		name        string
+		left, right [3]int
+		peaks       []int
+	}{
+		{"both are peaks, samples are equal", [3]int{1, 2, 3}, [3]int{3, 2, 1}, []int{2, 3}},
+		{"both are peaks, left sample is greater", [3]int{1, 2, 3}, [3]int{2, 2, 1}, []int{2}},
+		{"both are peaks, right sample is greater", [3]int{1, 2, 2}, [3]int{3, 2, 1}, []int{3}},
+		{"neither is a peak, samples are equal", [3]int{1, 2, 2}, [3]int{2, 3, 1}, []int{4}},
+		{"neither is a peak, left sample is greater", [3]int{1, 3, 2}, [3]int{1, 3, 1}, []int{1, 4}},
+		{"neither is a peak, right sample is greater", [3]int{1, 3, 1}, [3]int{2, 3, 1}, []int{1, 4}},
+		{"left side is a peak, samples are equal", [3]int{1, 2, 3}, [3]int{3, 4, 1}, []int{4}},
+		{"left side is a peak, left sample is greater", [3]int{1, 2, 4}, [3]int{3, 4, 1}, []int{2, 4}},
+		{"left side is a peak, right sample is greater", [3]int{1, 2, 3}, [3]int{4, 5, 1}, []int{4}},
+		{"right side is a peak, samples are equal", [3]int{1, 4, 3}, [3]int{3, 2, 1}, []int{1}},
+		{"right side is a peak, left sample is greater", [3]int{1, 5, 4}, [3]int{3, 2, 1}, []int{1}},
+		{"right side is a peak, right sample is greater", [3]int{1, 4, 3}, [3]int{4, 2, 1}, []int{1, 3}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			left, err := peakDetectThreeSamples(test.left[:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			right, err := peakDetectThreeSamples(test.right[:])
+			if err != nil {
+				t.Fatal(err)
+			}
+			merged, err := merge(left, right)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(test.peaks, merged.peaks) {
+				t.Errorf("merge(%v, %v): expected %v, got %v", test.left, test.right, test.peaks, merged.peaks)
+			}
+		})
+	}
+}
+
+func TestMergeOfNothing(t *testing.T) {
+	if _, err := merge(PrimaryPeaks[int]{}, PrimaryPeaks[int]{}); !errors.Is(err, ErrUnexpectedState) {
+		t.Errorf("expected %v, got %v", ErrUnexpectedState, err)
+	}
+	if _, err := mergeSecondary(SecondaryPeaks[int]{}, SecondaryPeaks[int]{}); !errors.Is(err, ErrUnexpectedState) {
+		t.Errorf("expected %v, got %v", ErrUnexpectedState, err)
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// The expected peaks of one level of the hierarchy, i.e., the samples of
+// the level, the peaks within them, and the peaks within the original samples.
+type level struct {
+	samples      []int
+	peaks        []int
+	primaryPeaks []int
+}
+
+func TestMultipass(t *testing.T) {
+	tests := []struct {
+		samples   []int
+		primary   []int
+		secondary level
+		tertiary  level
+	}{
+		// [1 8 3 5 7 2 3 6 9 0 1 3 12 1 4 8 2 4 1 9]
+		//    ^     ^       ^        ^     ^       ^
+		// [1 4 8 12 15 17 19]
+		//
+		//  1  4  8     12  15  17    19
+		// [8][7][9]   [12] [8] [4]   [9]
+		//  ^     ^      ^             ^
+		//
+		// After being merged, [9] that is to the left of [12],
+		// is no longer a peak, and therefore the peak property
+		// is removed from it.
+		//
+		// [8][7][9][12][8][4]   [9]
+		//  ^         ^
+		//
+		//  0        3         6
+		// [8][7][9][12][8][4][9]
+		//  ^         ^        ^
+		//
+		//    1                     12            19
+		// [1 8 3 5 7 2 3 6 9 0 1 3 12 1 4 8 2 4 1 9]
+		//    ^                      ^             ^
+		//
+		// Finally, at the tertiary level, only [12] remains.
+		{
+			samples:   []int{1, 8, 3, 5, 7, 2, 3, 6, 9, 0, 1, 3, 12, 1, 4, 8, 2, 4, 1, 9},
+			primary:   []int{1, 4, 8, 12, 15, 17, 19},
+			secondary: level{[]int{8, 7, 9, 12, 8, 4, 9}, []int{0, 3, 6}, []int{1, 12, 19}},
+			tertiary:  level{[]int{8, 12, 9}, []int{1}, []int{12}},
+		},
+		// [3 3 2 2 2 3 3 2 0 1]
+		//  ^ ^       ^ ^     ^
+		{
+			samples:   []int{3, 3, 2, 2, 2, 3, 3, 2, 0, 1},
+			primary:   []int{0, 1, 5, 6, 9},
+			secondary: level{[]int{3, 3, 3, 3, 1}, []int{0, 1, 2, 3}, []int{0, 1, 5, 6}},
+			tertiary:  level{[]int{3, 3, 3, 3}, []int{}, []int{}},
+		},
+		{
+			samples:   []int{3, 3, 3, 3, 3, 3, 0, 1, 0, 1},
+			primary:   []int{0, 1, 2, 3, 4, 5, 7, 9},
+			secondary: level{[]int{3, 3, 3, 3, 3, 3, 1, 1}, []int{0, 1, 2, 3, 4, 5}, []int{0, 1, 2, 3, 4, 5}},
+			tertiary:  level{[]int{3, 3, 3, 3, 3, 3}, []int{}, []int{}},
+		},
+		{
+			samples:   []int{3, 3, 3, 3, 3, 3, 0, 1, 0, 2},
+			primary:   []int{0, 1, 2, 3, 4, 5, 7, 9},
+			secondary: level{[]int{3, 3, 3, 3, 3, 3, 1, 2}, []int{0, 1, 2, 3, 4, 5, 7}, []int{0, 1, 2, 3, 4, 5, 9}},
+			tertiary:  level{[]int{3, 3, 3, 3, 3, 3, 2}, []int{0, 1, 2, 3, 4, 5}, []int{0, 1, 2, 3, 4, 5}},
+		},
+		{
+			samples:   []int{0, 1, 5, 2, 6, 8, 7, 1, 8},
+			primary:   []int{2, 5, 8},
+			secondary: level{[]int{5, 8, 8}, []int{1, 2}, []int{5, 8}},
+			tertiary:  level{[]int{8, 8}, []int{}, []int{}},
+		},
+		// All the samples on the 'left' side of the secondary level are
+		// the same value, and become peaks when merged with the 'right'.
+		{
+			samples:   []int{3, 3, 3, 3, 3, 3, 3, 3, 3, 0, 1, 0, 2},
+			primary:   []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 10, 12},
+			secondary: level{[]int{3, 3, 3, 3, 3, 3, 3, 3, 3, 1, 2}, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 10}, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 12}},
+			tertiary:  level{[]int{3, 3, 3, 3, 3, 3, 3, 3, 3, 2}, []int{0, 1, 2, 3, 4, 5, 6, 7, 8}, []int{0, 1, 2, 3, 4, 5, 6, 7, 8}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.samples), func(t *testing.T) {
+			primary := DetectPeaks(test.samples)
+			if !slices.Equal(test.primary, primary.GetPeaks()) {
+				t.Errorf("primary: expected %v, got %v", test.primary, primary.GetPeaks())
+			}
+			secondary := DetectPeaksInPrimary(primary)
+			expectLevel(t, "secondary", test.secondary, secondary)
+			tertiary := DetectPeaksInSecondary(secondary)
+			expectLevel(t, "tertiary", test.tertiary, tertiary)
+
+			iterated, ok := IteratePeakDetect(3, test.samples)
+			if !ok {
+				t.Fatal("expected the iteration to take place")
+			}
+			expectLevel(t, "iterated", test.tertiary, iterated)
+		})
+	}
+}
+
+// Regression test for the original indices of the secondary levels, which
+// DetectPeaksInSecondary took from the original peaks of the level below,
+// rather than from its primary peaks. Also, the merge made the primary peaks
+// of a cluster whose samples all become peaks share an array with its original
+// peaks, such that appending to the one overwrote the other.
+func TestSecondaryOriginalPeaks(t *testing.T) {
+	tests := []struct {
+		samples []int
+		levels  []level
+	}{
+		// The original peaks at level 3 were [2 3 8], rather than [2 3 4].
+		{
+			samples: []int{1, 0, 3, 3, 3, 0, 1, 0, 2},
+			levels: []level{
+				{[]int{1, 3, 3, 3, 1, 2}, []int{1, 2, 3, 5}, []int{2, 3, 4, 8}},
+				{[]int{3, 3, 3, 2}, []int{0, 1, 2}, []int{2, 3, 4}},
+				{[]int{3, 3, 3}, []int{}, []int{}},
+			},
+		},
+		// The 10 samples of the plateau become peaks when merged with the
+		// cluster to their right, which overwrote the primary peaks.
+		{
+			samples: []int{2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 0, 1, 0, 2},
+			levels: []level{
+				{[]int{2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 1, 2}, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 11}, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 13}},
+				{[]int{2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2}, []int{}, []int{}},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(fmt.Sprint(test.samples), func(t *testing.T) {
+			secondary := DetectPeaksInPrimary(DetectPeaks(test.samples))
+			for i, expected := range test.levels {
+				if i > 0 {
+					secondary = DetectPeaksInSecondary(secondary)
+				}
+				expectLevel(t, fmt.Sprintf("level %d", i+2), expected, secondary)
+			}
+			expectHierarchy(t, test.samples)
+		})
+	}
+}
+
+func expectLevel(t *testing.T, name string, expected level, p SecondaryPeaks[int]) {
+	t.Helper()
+	if !slices.Equal(expected.samples, p.GetSamples()) {
+		t.Errorf("%s: expected samples %v, got %v", name, expected.samples, p.GetSamples())
+	}
+	if !slices.Equal(expected.peaks, p.GetPeaks()) {
+		t.Errorf("%s: expected peaks %v, got %v", name, expected.peaks, p.GetPeaks())
+	}
+	if !slices.Equal(expected.primaryPeaks, p.GetPrimaryPeaks()) {
+		t.Errorf("%s: expected primary peaks %v, got %v", name, expected.primaryPeaks, p.GetPrimaryPeaks())
+	}
+}
+
+// Exhaustively enumerates the samples, and iterates the peak detection to
+// completion for each of them, verifying the peaks at every level.
+func TestIteratePeakDetect(t *testing.T) {
+	maxNumberOfPlaces := 10
+	if testing.Short() {
+		maxNumberOfPlaces = 8
+	}
+	for numberOfPlaces := 1; numberOfPlaces <= maxNumberOfPlaces; numberOfPlaces++ {
+		t.Run(fmt.Sprintf("places=%d", numberOfPlaces), func(t *testing.T) {
+			for _, samples := range product(t, []int{0, 1, 2, 3}, numberOfPlaces) {
+				iteratePeakDetect(t, samples)
+			}
+		})
+	}
+}
+
+func iteratePeakDetect(t *testing.T, samples []int) {
+	t.Helper()
+	primary := DetectPeaks(samples)
+	if !isValid[int](&primary) {
+		t.Fatalf("invalid primary peaks %v in samples %v", primary.GetPeaks(), samples)
+	}
+
+	secondary := DetectPeaksInPrimary(primary)
+	previous := primary.GetPeaks()
+	for {
+		if !isValid[int](&secondary) {
+			t.Fatalf("invalid peaks %v in level %d samples %v of samples %v",
+				secondary.GetPeaks(), secondary.GetLevel(), secondary.GetSamples(), samples)
+		}
+		expectInflated(t, samples, secondary)
+		for _, at := range secondary.GetPrimaryPeaks() {
+			if !slices.Contains(previous, at) {
+				t.Fatalf("level %d peak %d of samples %v is not a peak at level %d",
+					secondary.GetLevel(), at, samples, secondary.GetLevel()-1)
+			}
+		}
+		if secondary.GetPeakCount() == 0 {
+			break
+		}
+		if secondary.GetLevel() > len(samples) {
+			t.Fatalf("samples %v: iteration does not complete", samples)
+		}
+		previous = secondary.GetPrimaryPeaks()
+		secondary = DetectPeaksInSecondary(secondary)
+	}
+}
+
+// Verifies that inflating the primary values of the peaks sets each one of
+// the primary peaks to its original value, and all the other samples to zero.
+func expectInflated(t *testing.T, samples []int, p SecondaryPeaks[int]) {
+	t.Helper()
+	inflated := p.InflateWithCount(len(samples), PrimaryValuesOnly[int](&p))
+	for i, sample := range inflated {
+		expected := 0
+		if slices.Contains(p.GetPrimaryPeaks(), i) {
+			expected = samples[i]
+		}
+		if sample != expected {
+			t.Fatalf("samples %v: expected inflated level %d %v to have %d at %d",
+				samples, p.GetLevel(), inflated, expected, i)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import (
+	"fmt"
+	"golang.org/x/exp/constraints"
+	"math"
+	"slices"
+)
+
+// NaNPolicy determines how NaN samples are treated. A NaN is neither less,
+// greater, nor equal to any sample, including itself, therefore, it cannot
+// be ordered, and the peaks around it cannot be detected as they are.
+//
+// Infinities, on the other hand, are ordered, and are treated as any other
+// sample, e.g., two contiguous +Inf samples are a plateau.
+type NaNPolicy int
+
+const (
+	// NaNReject reports ErrNaNSample, if any of the samples is NaN.
+	NaNReject NaNPolicy = iota
+	// NaNSkip detects the peaks as if the NaN samples were not there at all,
+	// i.e., the samples on either side of a NaN are taken to be neighbors.
+	NaNSkip
+	// NaNGap splits the samples into segments at each NaN, and detects the
+	// peaks in each segment independently, as if each was a series of its own.
+	NaNGap
+	// NaNNegativeInfinity takes the NaN samples to be -Inf, i.e., less than
+	// any other sample, such that they are never peaks.
+	NaNNegativeInfinity
+)
+
+func (policy NaNPolicy) String() string {
+	switch policy {
+	case NaNReject:
+		return "reject"
+	case NaNSkip:
+		return "skip"
+	case NaNGap:
+		return "gap"
+	case NaNNegativeInfinity:
+		return "-Inf"
+	default:
+		return fmt.Sprintf("NaNPolicy(%d)", int(policy))
+	}
+}
+
+// DetectPeaksWithNaNPolicy detects the peaks in the samples, which may have
+// NaN samples among them, treating those as specified by the policy. The
+// samples of the result are the original samples, including the NaN ones,
+// and the peaks are the indices within them.
+func DetectPeaksWithNaNPolicy[T constraints.Float](samples []T, policy NaNPolicy) (PrimaryPeaks[T], error) {
+	views, err := detectLevelsWithNaNPolicy[T](1, samples, policy)
+	if err != nil {
+		return PrimaryPeaks[T]{}, err
+	}
+	result := CreatePeaksWith[T](slices.Clone(samples), originalLevels[T](views)[0])
+	result.level = 1
+	return result, nil
+}
+
+// IteratePeakDetectWithNaNPolicy detects the peaks at the same level as
+// IteratePeakDetect does, with the same number of iterations, in samples,
+// which may have NaN samples among them, treating those as specified by the
+// policy. The primary samples of the result are the original samples,
+// including the NaN ones, and the primary peaks are the indices within them.
+//
+// With NaNGap, the peaks at each level are those of every segment, which
+// has not run out of peaks below that level.
+func IteratePeakDetectWithNaNPolicy[T constraints.Float](iterations uint, samples []T, policy NaNPolicy) (SecondaryPeaks[T], bool, error) {
+	if iterations == 0 {
+		return SecondaryPeaks[T]{}, false, nil
+	}
+	views, err := detectLevelsWithNaNPolicy[T](max(iterations, 2), samples, policy)
+	if err != nil {
+		return SecondaryPeaks[T]{}, false, err
+	}
+	levels := originalLevels[T](views)
+	if len(levels) == 1 {
+		levels = append(levels, []int{})
+	}
+	below, peaks := levels[len(levels)-2], levels[len(levels)-1]
+	secondary := SecondaryPeaks[T]{
+		PrimaryPeaks:   PrimaryPeaks[T]{make([]T, len(below)), make([]int, 0, len(peaks)), len(levels)},
+		primarySamples: slices.Clone(samples),
+		primaryPeaks:   peaks,
+		originalPeaks:  below,
+	}
+	// The peaks of the level are among those of the level below it, and both
+	// are in the order of the original samples.
+	j := 0
+	for i, at := range below {
+		secondary.samples[i] = samples[at]
+		if j < len(peaks) && peaks[j] == at {
+			secondary.peaks = append(secondary.peaks, i)
+			j++
+		}
+	}
+	return secondary, true, nil
+}
+
+// IteratePeakDetectSeriesWithNaNPolicy is IteratePeakDetectSeries, for series
+// whose values may be NaN, which are treated as specified by the policy.
+func IteratePeakDetectSeriesWithNaNPolicy[T constraints.Float](iterations uint, s Series[T], policy NaNPolicy) (SeriesPeaks[T], bool, error) {
+	levels, ok, err := IteratePeakDetectSeriesLevelsWithNaNPolicy[T](iterations, s, policy)
+	if err != nil || !ok {
+		return SeriesPeaks[T]{}, ok, err
+	}
+	return levels[len(levels)-1], true, nil
+}
+
+// IteratePeakDetectSeriesLevelsWithNaNPolicy is IteratePeakDetectSeriesLevels,
+// for series whose values may be NaN, which are treated as specified by the
+// policy. The prominences are those of the peaks among the samples, as the
+// policy has them be ordered, e.g., with NaNGap, within their segment.
+func IteratePeakDetectSeriesLevelsWithNaNPolicy[T constraints.Float](iterations uint, s Series[T], policy NaNPolicy) ([]SeriesPeaks[T], bool, error) {
+	if err := s.validate(); err != nil {
+		return nil, false, err
+	}
+	if iterations == 0 {
+		return nil, false, nil
+	}
+	views, err := detectLevelsWithNaNPolicy[T](max(iterations, 2), s.Values, policy)
+	if err != nil {
+		return nil, false, err
+	}
+	levels := originalLevels[T](views)
+	peaks := make([]SeriesPeaks[T], max(len(levels), 2))
+	for i := range peaks {
+		var indices []int
+		prominences := make([]T, 0)
+		if i < len(levels) {
+			indices = levels[i]
+			for _, v := range views {
+				if i < len(v.levels) {
+					prominences = append(prominences, Prominences[T](v.samples, v.levels[i])...)
+				}
+			}
+		}
+		peaks[i] = s.peaksAt(i+1, indices, prominences)
+	}
+	return peaks, true, nil
+}
+
+// The samples, as the policy has them be ordered, in which the peaks are
+// detected, along with the peaks at each level, as their indices within them.
+type nanView[T constraints.Float] struct {
+	samples []T
+	// The index within the original samples of each of the samples, unless
+	// it is nil, in which case the samples start at the offset within them.
+	indices []int
+	offset  int
+	levels  [][]int
+}
+
+func (v *nanView[T]) original(at int) int {
+	if v.indices != nil {
+		return v.indices[at]
+	}
+	return v.offset + at
+}
+
+// Detects the peaks in each of the views of the samples, at each level, up
+// to, and including, the specified level, or the first level without any
+// peaks, if that comes first.
+func detectLevelsWithNaNPolicy[T constraints.Float](levels uint, samples []T, policy NaNPolicy) ([]nanView[T], error) {
+	views, err := nanViews[T](samples, policy)
+	if err != nil {
+		return nil, err
+	}
+	for i := range views {
+		if views[i].levels, err = detectLevels[T](levels, views[i].samples); err != nil {
+			if policy == NaNGap {
+				return nil, fmt.Errorf("segment at sample %d: %w", views[i].offset, err)
+			}
+			return nil, err
+		}
+	}
+	return views, nil
+}
+
+// Returns the indices within the original samples of the peaks at each level,
+// in all the views. A view, whose detection stopped short of a level, has no
+// peaks at that level. There is always at least the primary level.
+func originalLevels[T constraints.Float](views []nanView[T]) [][]int {
+	count := 1
+	for _, v := range views {
+		count = max(count, len(v.levels))
+	}
+	levels := make([][]int, count)
+	for i := range levels {
+		levels[i] = make([]int, 0)
+		for _, v := range views {
+			if i < len(v.levels) {
+				for _, at := range v.levels[i] {
+					levels[i] = append(levels[i], v.original(at))
+				}
+			}
+		}
+	}
+	return levels
+}
+
+// Returns the views of the samples, as the policy has them be ordered, i.e.,
+// the samples themselves, if none are NaN, the samples that are not NaN, each
+// segment between the NaN samples, or the samples with -Inf in place of NaN.
+func nanViews[T constraints.Float](samples []T, policy NaNPolicy) ([]nanView[T], error) {
+	switch policy {
+	case NaNReject:
+		if err := findNaN[T](samples, nil, nil); err != nil {
+			return nil, err
+		}
+		return []nanView[T]{{samples: samples}}, nil
+	case NaNSkip:
+		view := nanView[T]{samples: make([]T, 0, len(samples)), indices: make([]int, 0, len(samples))}
+		for i, sample := range samples {
+			if !isNaN[T](sample) {
+				view.samples = append(view.samples, sample)
+				view.indices = append(view.indices, i)
+			}
+		}
+		return []nanView[T]{view}, nil
+	case NaNGap:
+		views := make([]nanView[T], 0)
+		for start := 0; start < len(samples); {
+			if isNaN[T](samples[start]) {
+				start++
+				continue
+			}
+			end := start + 1
+			for end < len(samples) && !isNaN[T](samples[end]) {
+				end++
+			}
+			views = append(views, nanView[T]{samples: samples[start:end], offset: start})
+			start = end
+		}
+		return views, nil
+	case NaNNegativeInfinity:
+		values := make([]T, len(samples))
+		for i, sample := range samples {
+			if isNaN[T](sample) {
+				values[i] = T(math.Inf(-1))
+			} else {
+				values[i] = sample
+			}
+		}
+		return []nanView[T]{{samples: values}}, nil
+	default:
+		return nil, fmt.Errorf("unknown NaN policy: %v", policy)
+	}
+}
+
+func isNaN[T constraints.Float](sample T) bool {
+	return sample != sample
+}
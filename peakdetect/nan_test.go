@@ -0,0 +1,222 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+)
+
+var nan = math.NaN()
+
+func TestDetectPeaksWithNaNPolicy(t *testing.T) {
+	tests := []struct {
+		samples []float64
+		// The peaks with each policy, other than NaNReject.
+		skip, gap, negativeInfinity []int
+	}{
+		{[]float64{}, []int{}, []int{}, []int{}},
+		{[]float64{nan}, []int{}, []int{}, []int{}},
+		{[]float64{nan, nan}, []int{}, []int{}, []int{}},
+		{[]float64{1, 3, 2}, []int{1}, []int{1}, []int{1}},
+		// Leading.
+		{[]float64{nan, 3, 1, 2, 1}, []int{1, 3}, []int{1, 3}, []int{1, 3}},
+		{[]float64{nan, 1, nan, 1, 2}, []int{4}, []int{4}, []int{1, 4}},
+		// Trailing.
+		{[]float64{1, 2, 1, 3, nan}, []int{1, 3}, []int{1, 3}, []int{1, 3}},
+		{[]float64{3, nan, 3, nan}, []int{}, []int{}, []int{0, 2}},
+		// Consecutive.
+		{[]float64{1, 3, nan, nan, 3, 1}, []int{1, 4}, []int{1, 4}, []int{1, 4}},
+		{[]float64{2, nan, 1, nan, nan, 2}, []int{0, 5}, []int{}, []int{0, 2, 5}},
+		{[]float64{nan, nan, 1, 2, nan, nan, 2, 1, nan, nan}, []int{3, 6}, []int{3, 6}, []int{3, 6}},
+	}
+	for _, test := range tests {
+		for _, expected := range []struct {
+			policy NaNPolicy
+			peaks  []int
+		}{
+			{NaNSkip, test.skip},
+			{NaNGap, test.gap},
+			{NaNNegativeInfinity, test.negativeInfinity},
+		} {
+			peaks, err := DetectPeaksWithNaNPolicy(test.samples, expected.policy)
+			if err != nil {
+				t.Fatalf("%v, %v: %v", test.samples, expected.policy, err)
+			}
+			if !slices.Equal(expected.peaks, peaks.GetPeaks()) {
+				t.Errorf("%v, %v: expected %v, got %v", test.samples, expected.policy, expected.peaks, peaks.GetPeaks())
+			}
+			if !equalSamples(test.samples, peaks.GetSamples()) {
+				t.Errorf("%v, %v: expected the original samples, got %v", test.samples, expected.policy, peaks.GetSamples())
+			}
+		}
+
+		peaks, err := DetectPeaksWithNaNPolicy(test.samples, NaNReject)
+		if slices.ContainsFunc(test.samples, math.IsNaN) {
+			if !errors.Is(err, ErrNaNSample) {
+				t.Errorf("%v, %v: expected %v, got %v", test.samples, NaNReject, ErrNaNSample, err)
+			}
+		} else if err != nil || !slices.Equal(test.skip, peaks.GetPeaks()) {
+			t.Errorf("%v, %v: expected %v, got %v, %v", test.samples, NaNReject, test.skip, peaks.GetPeaks(), err)
+		}
+	}
+	if _, err := DetectPeaksWithNaNPolicy([]float64{1}, NaNPolicy(-1)); err == nil {
+		t.Errorf("expected an unknown policy to be reported")
+	}
+}
+
+// With NaNSkip, and NaNNegativeInfinity, each level is the level that
+// IteratePeakDetect detects, in the samples without the NaN samples, or
+// with -Inf in place of them, respectively.
+func TestIteratePeakDetectWithNaNPolicy(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 500; n++ {
+		samples := make([]float64, r.Intn(100))
+		for i := range samples {
+			if samples[i] = float64(r.Intn(8)); samples[i] == 0 {
+				samples[i] = nan
+			}
+		}
+		var skipped, negative []float64
+		var indices []int
+		for i, sample := range samples {
+			if math.IsNaN(sample) {
+				negative = append(negative, math.Inf(-1))
+				continue
+			}
+			skipped = append(skipped, sample)
+			indices = append(indices, i)
+			negative = append(negative, sample)
+		}
+		original := func(at int) int { return indices[at] }
+		same := func(at int) int { return at }
+		for iterations := uint(1); iterations <= 5; iterations++ {
+			expected, _ := IteratePeakDetect(iterations, skipped)
+			expectNaNPolicyLevel(t, iterations, samples, NaNSkip, expected, original)
+			expected, _ = IteratePeakDetect(iterations, negative)
+			expectNaNPolicyLevel(t, iterations, samples, NaNNegativeInfinity, expected, same)
+		}
+	}
+}
+
+func expectNaNPolicyLevel(t *testing.T, iterations uint, samples []float64, policy NaNPolicy, expected SecondaryPeaks[float64], original func(int) int) {
+	t.Helper()
+	secondary, ok, err := IteratePeakDetectWithNaNPolicy(iterations, samples, policy)
+	if err != nil || !ok {
+		t.Fatalf("%v, %v, %d: %v, %v", samples, policy, iterations, ok, err)
+	}
+	if expected.GetLevel() != secondary.GetLevel() {
+		t.Fatalf("%v, %v, %d: expected level %d, got %d", samples, policy, iterations, expected.GetLevel(), secondary.GetLevel())
+	}
+	primaryPeaks := make([]int, 0)
+	for _, at := range expected.GetPrimaryPeaks() {
+		primaryPeaks = append(primaryPeaks, original(at))
+	}
+	if !slices.Equal(primaryPeaks, secondary.GetPrimaryPeaks()) {
+		t.Fatalf("%v, %v, %d: expected %v, got %v", samples, policy, iterations, primaryPeaks, secondary.GetPrimaryPeaks())
+	}
+	if !slices.Equal(expected.GetPeaks(), secondary.GetPeaks()) || !slices.Equal(expected.GetSamples(), secondary.GetSamples()) {
+		t.Fatalf("%v, %v, %d: expected %v in %v, got %v in %v", samples, policy, iterations,
+			expected.GetPeaks(), expected.GetSamples(), secondary.GetPeaks(), secondary.GetSamples())
+	}
+	if !equalSamples(samples, secondary.GetPrimarySamples()) {
+		t.Fatalf("%v, %v, %d: expected the original samples, got %v", samples, policy, iterations, secondary.GetPrimarySamples())
+	}
+}
+
+// With NaNGap, the segments run out of peaks at different levels.
+func TestIteratePeakDetectWithNaNGap(t *testing.T) {
+	samples := []float64{nan, 1, 3, 2, 5, 1, nan, nan, 1, 2, 1, nan}
+	tests := []struct {
+		iterations uint
+		level      int
+		samples    []float64
+		peaks      []int
+		primary    []int
+	}{
+		{1, 2, []float64{3, 5, 2}, []int{1}, []int{4}},
+		{2, 2, []float64{3, 5, 2}, []int{1}, []int{4}},
+		{3, 3, []float64{5}, []int{}, []int{}},
+		{4, 3, []float64{5}, []int{}, []int{}},
+	}
+	for _, test := range tests {
+		secondary, ok, err := IteratePeakDetectWithNaNPolicy(test.iterations, samples, NaNGap)
+		if err != nil || !ok {
+			t.Fatalf("%d: %v, %v", test.iterations, ok, err)
+		}
+		if test.level != secondary.GetLevel() ||
+			!slices.Equal(test.samples, secondary.GetSamples()) ||
+			!slices.Equal(test.peaks, secondary.GetPeaks()) ||
+			!slices.Equal(test.primary, secondary.GetPrimaryPeaks()) {
+			t.Errorf("%d: expected level %d, %v in %v, at %v, got level %d, %v in %v, at %v", test.iterations,
+				test.level, test.peaks, test.samples, test.primary,
+				secondary.GetLevel(), secondary.GetPeaks(), secondary.GetSamples(), secondary.GetPrimaryPeaks())
+		}
+	}
+	if _, ok, err := IteratePeakDetectWithNaNPolicy(0, samples, NaNGap); ok || err != nil {
+		t.Errorf("expected no iterations, got %v, %v", ok, err)
+	}
+	if _, _, err := IteratePeakDetectWithNaNPolicy(2, samples, NaNReject); !errors.Is(err, ErrNaNSample) {
+		t.Errorf("expected %v, got %v", ErrNaNSample, err)
+	}
+}
+
+func TestIteratePeakDetectSeriesWithNaNPolicy(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	values := []float64{1, 5, nan, 2, 4, 1}
+	timestamps := make([]time.Time, len(values))
+	for i := range timestamps {
+		timestamps[i] = start.Add(time.Duration(i) * time.Minute)
+	}
+	s := CreateSeries(timestamps, values)
+	tests := []struct {
+		policy      NaNPolicy
+		indices     []int
+		prominences []float64
+		secondary   []int
+	}{
+		// The saddle between the peaks is the sample beyond the NaN.
+		{NaNSkip, []int{1, 4}, []float64{4, 2}, []int{1}},
+		// Each peak is the tallest, and the only, peak in its segment.
+		{NaNGap, []int{1, 4}, []float64{4, 3}, []int{}},
+		{NaNNegativeInfinity, []int{1, 4}, []float64{math.Inf(1), math.Inf(1)}, []int{1}},
+	}
+	for _, test := range tests {
+		levels, ok, err := IteratePeakDetectSeriesLevelsWithNaNPolicy(1, s, test.policy)
+		if err != nil || !ok {
+			t.Fatalf("%v: %v, %v", test.policy, ok, err)
+		}
+		primary := levels[0]
+		if !slices.Equal(test.indices, primary.Indices) || !slices.Equal(test.prominences, primary.Prominences) {
+			t.Errorf("%v: expected %v, with %v, got %v, with %v", test.policy, test.indices, test.prominences, primary.Indices, primary.Prominences)
+		}
+		for i, at := range primary.Indices {
+			if !primary.Timestamps[i].Equal(timestamps[at]) || primary.Values[i] != values[at] {
+				t.Errorf("%v: expected peak %d at %v, got %v, %v", test.policy, at, timestamps[at], primary.Timestamps[i], primary.Values[i])
+			}
+		}
+		peaks, ok, err := IteratePeakDetectSeriesWithNaNPolicy(1, s, test.policy)
+		if err != nil || !ok || peaks.Level != 2 || !slices.Equal(test.secondary, peaks.Indices) {
+			t.Errorf("%v: expected %v at level 2, got %v at level %d, %v", test.policy, test.secondary, peaks.Indices, peaks.Level, err)
+		}
+	}
+	if _, _, err := IteratePeakDetectSeriesWithNaNPolicy(1, s, NaNReject); !errors.Is(err, ErrNaNSample) {
+		t.Errorf("expected %v, got %v", ErrNaNSample, err)
+	}
+	if _, _, err := IteratePeakDetectSeriesWithNaNPolicy(1, CreateSeries(timestamps[1:], values), NaNSkip); !errors.Is(err, ErrInvalidSampleCount) {
+		t.Errorf("expected %v, got %v", ErrInvalidSampleCount, err)
+	}
+}
+
+// Tells whether the samples are the same, where NaN is the same as NaN.
+func equalSamples(expected, samples []float64) bool {
+	return slices.EqualFunc(expected, samples, func(a, b float64) bool {
+		return a == b || math.IsNaN(a) && math.IsNaN(b)
+	})
+}
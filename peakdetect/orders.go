@@ -0,0 +1,35 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+// DetectPeakOrders returns, for every sample, the highest level of the peak
+// hierarchy at which the sample is still a peak. The primary peaks, i.e.,
+// those found by DetectPeaks, are at level 1. The peaks found among those
+// by DetectPeaksInPrimary are at level 2, and every subsequent application
+// of DetectPeaksInSecondary adds another level. Samples that are not peaks
+// at all are at level 0.
+//
+// All the levels are computed in a single pass over the hierarchy, the same
+// one made by IteratePeakDetectToCompletion. Returns nil if the samples
+// cannot be ordered, e.g., due to a NaN. Use DetectPeakOrdersE to find out why.
+func DetectPeakOrders[T Number](samples []T) []int {
+	orders, _ := DetectPeakOrdersE[T](samples)
+	return orders
+}
+
+func DetectPeakOrdersE[T Number](samples []T) ([]int, error) {
+	orders := make([]int, len(samples))
+	_, err := iteratePeakDetectToCompletion[T](samples, func(level int, peaks []int) {
+		// Every peak at this level was also a peak at all the lower
+		// levels, therefore, the level only ever increases.
+		for _, at := range peaks {
+			orders[at] = level
+		}
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestDetectPeakOrders(t *testing.T) {
+	tests := []struct {
+		samples []int
+		orders  []int
+	}{
+		{[]int{}, []int{}},
+		{[]int{1}, []int{0}},
+		{[]int{1, 1, 1}, []int{0, 0, 0}},
+		{[]int{1, 2}, []int{0, 1}},
+		{[]int{1, 3, 2, 5, 1}, []int{0, 1, 0, 2, 0}},
+		// The plateaus are peaks at every level, at which the plateau is.
+		{[]int{0, 5, 5, 0, 3, 0, 5, 0}, []int{0, 2, 2, 0, 1, 0, 2, 0}},
+		// See TestMultipass.
+		{
+			[]int{1, 8, 3, 5, 7, 2, 3, 6, 9, 0, 1, 3, 12, 1, 4, 8, 2, 4, 1, 9},
+			[]int{0, 2, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 3, 0, 0, 1, 0, 1, 0, 2},
+		},
+	}
+	for _, test := range tests {
+		orders, err := DetectPeakOrdersE(test.samples)
+		if err != nil {
+			t.Fatalf("%v: %v", test.samples, err)
+		}
+		if !slices.Equal(test.orders, orders) {
+			t.Errorf("%v: expected %v, got %v", test.samples, test.orders, orders)
+		}
+	}
+	if orders, err := DetectPeakOrdersE([]float64{1, math.NaN(), 1}); !errors.Is(err, ErrNaNSample) || orders != nil {
+		t.Errorf("expected %v, got %v, %v", ErrNaNSample, orders, err)
+	}
+}
+
+// The order of every sample is the highest level, at which the sample is
+// among the primary peaks of the level, as detected level by level.
+func TestDetectPeakOrdersLevels(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 500; n++ {
+		samples := make([]int, r.Intn(200))
+		for i := range samples {
+			samples[i] = r.Intn(10)
+		}
+		expected := make([]int, len(samples))
+		primary := DetectPeaks(samples)
+		for _, at := range primary.GetPeaks() {
+			expected[at] = 1
+		}
+		for secondary := DetectPeaksInPrimary(primary); secondary.GetPeakCount() > 0; secondary = DetectPeaksInSecondary(secondary) {
+			for _, at := range secondary.GetPrimaryPeaks() {
+				expected[at] = secondary.GetLevel()
+			}
+		}
+		if orders := DetectPeakOrders(samples); !slices.Equal(expected, orders) {
+			t.Fatalf("%v: expected %v, got %v", samples, expected, orders)
+		}
+	}
+}
@@ -5,8 +5,8 @@
 package peakdetect
 
 import (
+	"fmt"
 	"golang.org/x/exp/constraints"
-	"log"
 )
 
 type Number interface {
@@ -23,6 +23,7 @@ type Peaks[T Number] interface {
 type PrimaryPeaks[T Number] struct {
 	samples []T
 	peaks   []int
+	level   int
 }
 
 type SecondaryPeaks[T Number] struct {
@@ -37,7 +38,7 @@ func CreatePeaks[T Number](a, b, c T, peaks []int) PrimaryPeaks[T] {
 }
 
 func CreatePeaksWith[T Number](samples []T, peaks []int) PrimaryPeaks[T] {
-	return PrimaryPeaks[T]{samples, peaks}
+	return PrimaryPeaks[T]{samples, peaks, 0}
 }
 
 func CreateSecondaryPeaksWith[T Number](p PrimaryPeaks[T], primaryPeaks []int, originalPeaks []int) SecondaryPeaks[T] {
@@ -60,6 +61,13 @@ func (p *PrimaryPeaks[_]) GetPeaks() []int {
 	return p.peaks
 }
 
+// GetLevel returns the level of the peak hierarchy, at which the peaks
+// were detected, i.e., 1 for the peaks detected by DetectPeaks, 2 for
+// those detected by DetectPeaksInPrimary, and so on.
+func (p *PrimaryPeaks[_]) GetLevel() int {
+	return p.level
+}
+
 func (p *SecondaryPeaks[T]) GetPrimarySamples() []T {
 	return p.primarySamples
 }
@@ -76,21 +84,16 @@ func (p *PrimaryPeaks[T]) getLastSample() T {
 	return p.samples[len(p.samples)-1]
 }
 
+// The peaks are always in ascending order, therefore, if the first sample
+// is a peak, it must be the first peak, and there is no need to search.
 func (p *PrimaryPeaks[_]) isFirstSamplePeak() bool {
-	return p.isSampleFoundInPeaks(0)
+	return len(p.peaks) > 0 && p.peaks[0] == 0
 }
 
+// The peaks are always in ascending order, therefore, if the last sample
+// is a peak, it must be the last peak, and there is no need to search.
 func (p *PrimaryPeaks[_]) isLastSamplePeak() bool {
-	return p.isSampleFoundInPeaks(len(p.samples) - 1)
-}
-
-func (p *PrimaryPeaks[_]) isSampleFoundInPeaks(sampleIndex int) bool {
-	for i := 0; i < len(p.peaks); i++ {
-		if p.peaks[i] == sampleIndex {
-			return true
-		}
-	}
-	return false
+	return len(p.peaks) > 0 && p.peaks[len(p.peaks)-1] == len(p.samples)-1
 }
 
 func (p *PrimaryPeaks[_]) createAllPeaks() []int {
@@ -106,28 +109,57 @@ func (p *PrimaryPeaks[_]) createAllPeaks() []int {
 // not peaks, are set to zero, and wherein all peaks are set
 // to their original peak values
 func (p *PrimaryPeaks[T]) Inflate() []T {
-	return p.InflateWithCount(len(p.samples), p)
+	peaks, _ := p.InflateE()
+	return peaks
 }
 
+func (p *PrimaryPeaks[T]) InflateE() ([]T, error) {
+	return p.InflateWithCountE(len(p.samples), p)
+}
+
+// InflateWithCount returns nil if the peaks do not fit into the
+// number of samples. Use InflateWithCountE to find out why.
 func (p *PrimaryPeaks[T]) InflateWithCount(samples int, from Peaks[T]) []T {
+	peaks, _ := p.InflateWithCountE(samples, from)
+	return peaks
+}
+
+func (p *PrimaryPeaks[T]) InflateWithCountE(samples int, from Peaks[T]) ([]T, error) {
 	if samples < 0 {
-		log.Fatal("Number of samples must be greater than zero")
+		return nil, fmt.Errorf("%w: number of samples must not be negative", ErrInvalidSampleCount)
 	}
 	peaks := make([]T, samples)
-	p.InflateInto(peaks, from)
-	return peaks
+	if err := p.InflateIntoE(peaks, from); err != nil {
+		return nil, err
+	}
+	return peaks, nil
 }
 
+// InflateInto leaves the array untouched if the peaks do not fit
+// into it. Use InflateIntoE to find out why.
 func (p *PrimaryPeaks[T]) InflateInto(peaks []T, from Peaks[T]) {
+	_ = p.InflateIntoE(peaks, from)
+}
+
+func (p *PrimaryPeaks[T]) InflateIntoE(peaks []T, from Peaks[T]) error {
 	if len(p.peaks) > len(peaks) {
-		log.Fatal("Invalid state: more peaks than space for samples in the array")
+		return fmt.Errorf("%w: more peaks than space for samples in the array", ErrInvalidSampleCount)
 	}
 	fromPeaks := from.GetPeaks()
 	fromSamples := from.GetSamples()
+	if len(p.peaks) > len(fromPeaks) {
+		return fmt.Errorf("%w: more peaks than are found in the source", ErrInvalidSampleCount)
+	}
+	for i := 0; i < len(p.peaks); i++ {
+		if at := fromPeaks[i]; at >= len(peaks) || at >= len(fromSamples) {
+			return fmt.Errorf("%w: peak at %d is outside of the array", ErrInvalidSampleCount, at)
+		}
+	}
 	for i := 0; i < len(p.peaks); i++ {
 		at := fromPeaks[i]
 		peaks[at] = fromSamples[at]
 	}
+	return nil
 }
 
 func AlignPeaksToSamplePositions(sampleCount int, peaks []int) []int {
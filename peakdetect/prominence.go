@@ -0,0 +1,83 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+// Prominences returns the topographic prominence of each of the peaks, i.e.,
+// how high the peak rises above the highest saddle connecting it to a taller
+// peak. The saddle on either side of the peak is the lowest sample between
+// the peak and the nearest taller sample on that side. If there is no taller
+// sample on either side, the peak is the tallest one, and its prominence is
+// its height above the lowest sample.
+//
+// Samples of a plateau are not taller than one another, therefore, all the
+// samples of a plateau have the same prominence. Neither are two separate
+// peaks of the same height, therefore, if no sample is taller than either,
+// both are the tallest one.
+//
+// The prominences are also returned along with the peaks, by GetPeakRegions,
+// and by the detection of the peaks of a Series.
+func Prominences[T Number](samples []T, peaks []int) []T {
+	leftSaddles, leftTaller := saddles[T](samples, 0, len(samples), 1)
+	rightSaddles, rightTaller := saddles[T](samples, len(samples)-1, -1, -1)
+	prominences := make([]T, len(peaks))
+	for i, at := range peaks {
+		var saddle T
+		if leftTaller[at] && rightTaller[at] {
+			saddle = max(leftSaddles[at], rightSaddles[at])
+		} else if leftTaller[at] {
+			saddle = leftSaddles[at]
+		} else if rightTaller[at] {
+			saddle = rightSaddles[at]
+		} else {
+			saddle = min(leftSaddles[at], rightSaddles[at])
+		}
+		prominences[i] = samples[at] - saddle
+	}
+	return prominences
+}
+
+// GetProminences returns the prominence of each of the peaks, in the same
+// order as returned by GetPeaks.
+func (p *PrimaryPeaks[T]) GetProminences() []T {
+	return Prominences[T](p.samples, p.peaks)
+}
+
+// GetPrimaryProminences returns the prominence of each of the peaks, in the
+// same order as returned by GetPrimaryPeaks. The prominence is a property of
+// the original samples, therefore, a peak has the same prominence at every
+// level of the hierarchy.
+func (p *SecondaryPeaks[T]) GetPrimaryProminences() []T {
+	return Prominences[T](p.primarySamples, p.primaryPeaks)
+}
+
+// Walks the samples in the given direction, and computes, for every sample,
+// the lowest sample between it, inclusive, and the nearest taller sample
+// behind it, exclusive. Also reports whether there is a taller sample at all.
+//
+// We keep a stack of samples, each one taller than the one above it, along
+// with the lowest sample between it, and the one below it. When we reach
+// the next sample, all the samples that are not taller are popped, and their
+// lowest samples folded into that of the new sample. What remains on top of
+// the stack is the nearest taller sample.
+func saddles[T Number](samples []T, from, to, step int) ([]T, []bool) {
+	type entry struct {
+		at     int
+		lowest T
+	}
+	lowest := make([]T, len(samples))
+	taller := make([]bool, len(samples))
+	stack := make([]entry, 0)
+	for i := from; i != to; i += step {
+		m := samples[i]
+		for len(stack) > 0 && samples[stack[len(stack)-1].at] <= samples[i] {
+			m = min(m, stack[len(stack)-1].lowest)
+			stack = stack[:len(stack)-1]
+		}
+		lowest[i] = m
+		taller[i] = len(stack) > 0
+		stack = append(stack, entry{i, m})
+	}
+	return lowest, taller
+}
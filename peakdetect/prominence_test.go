@@ -0,0 +1,75 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestProminences(t *testing.T) {
+	tests := []struct {
+		name        string
+		samples     []int
+		peaks       []int
+		prominences []int
+	}{
+		{"none", []int{1, 1, 1}, []int{}, []int{}},
+		{"saddle", []int{0, 3, 1, 5, 2}, []int{1, 3}, []int{2, 5}},
+		{"plateau", []int{1, 4, 4, 4, 2, 6, 0}, []int{1, 2, 3, 5}, []int{2, 2, 2, 6}},
+		{"first and last", []int{5, 1, 3}, []int{0, 2}, []int{4, 2}},
+		{"equal heights", []int{0, 5, 4, 5, 1}, []int{1, 3}, []int{5, 5}},
+		{"equal heights below a taller peak", []int{0, 5, 2, 5, 1, 7, 0}, []int{1, 3, 5}, []int{4, 4, 7}},
+		{"higher saddle of the two", []int{1, 9, 3, 6, 2, 8}, []int{1, 3, 5}, []int{8, 3, 6}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			primary := DetectPeaks(test.samples)
+			if !slices.Equal(test.peaks, primary.GetPeaks()) {
+				t.Fatalf("expected peaks %v, got %v", test.peaks, primary.GetPeaks())
+			}
+			if prominences := primary.GetProminences(); !slices.Equal(test.prominences, prominences) {
+				t.Errorf("expected %v, got %v", test.prominences, prominences)
+			}
+		})
+	}
+}
+
+func TestProminencesUnsigned(t *testing.T) {
+	primary := DetectPeaks([]uint8{255, 0, 3, 0, 255})
+	if expected := []uint8{255, 3, 255}; !slices.Equal(expected, primary.GetProminences()) {
+		t.Errorf("expected %v, got %v", expected, primary.GetProminences())
+	}
+}
+
+// The prominence is that of the original samples, at every level, and is
+// returned along with the peaks.
+func TestProminencesWithPeaks(t *testing.T) {
+	samples := []int{1, 8, 3, 5, 7, 2, 3, 6, 9, 0, 1, 3, 12, 1, 4, 8, 2, 4, 1, 9}
+	secondary, _ := IteratePeakDetect(2, samples)
+	if expected := []int{6, 12, 8}; !slices.Equal(expected, secondary.GetPrimaryProminences()) {
+		t.Errorf("expected %v, got %v", expected, secondary.GetPrimaryProminences())
+	}
+	regions := secondary.GetPeakRegions()
+	for i, region := range regions {
+		if region.Prominence != secondary.GetPrimaryProminences()[i] {
+			t.Errorf("expected region %v to have prominence %d", region, secondary.GetPrimaryProminences()[i])
+		}
+	}
+	plateau := DetectPeaks([]int{1, 4, 4, 4, 2, 6, 0})
+	if regions := plateau.GetPeakRegions(); len(regions) != 2 || regions[0].Prominence != 2 || regions[1].Prominence != 6 {
+		t.Errorf("expected prominences 2, and 6, got %v", regions)
+	}
+
+	timestamps := make([]time.Time, len(samples))
+	peaks, _, err := IteratePeakDetectSeries(2, CreateSeries(timestamps, samples))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(secondary.GetPrimaryProminences(), peaks.Prominences) {
+		t.Errorf("expected %v, got %v", secondary.GetPrimaryProminences(), peaks.Prominences)
+	}
+}
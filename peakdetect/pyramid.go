@@ -0,0 +1,135 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+// PeakPyramid holds every level of the peak hierarchy, from the primary
+// peaks at level 1, up to the last level detected, rather than only the
+// last of them, as IteratePeakDetect does. The levels are numbered as
+// GetLevel numbers them, and at each of them, the peaks are described by
+// their indices within the original samples, irrespective of the level.
+//
+// The primary level is always present, even if it has no peaks, whereas
+// each of the levels above it is only present if it has some peaks.
+type PeakPyramid[T Number] struct {
+	primary   PrimaryPeaks[T]
+	secondary []SecondaryPeaks[T]
+}
+
+// DetectPeakPyramid detects the levels of the peak hierarchy, up to, and
+// including, the specified level, or up to the last level that has any
+// peaks, if that comes first. The primary level is always detected, even
+// if the level is 0. All the levels are detected in a single pass, each
+// from the one below it. Returns an empty pyramid if the samples cannot be
+// ordered, e.g., due to a NaN. Use DetectPeakPyramidE to find out why.
+func DetectPeakPyramid[T Number](levels uint, samples []T) PeakPyramid[T] {
+	pyramid, _ := DetectPeakPyramidE[T](levels, samples)
+	return pyramid
+}
+
+func DetectPeakPyramidE[T Number](levels uint, samples []T) (PeakPyramid[T], error) {
+	return detectPeakPyramid[T](samples, func(level int) bool {
+		return uint(level) >= levels
+	})
+}
+
+// DetectPeakPyramidToCompletion detects all the levels of the peak hierarchy
+// that have any peaks, i.e., all those that IteratePeakDetectToCompletion
+// goes through.
+func DetectPeakPyramidToCompletion[T Number](samples []T) PeakPyramid[T] {
+	pyramid, _ := DetectPeakPyramidToCompletionE[T](samples)
+	return pyramid
+}
+
+func DetectPeakPyramidToCompletionE[T Number](samples []T) (PeakPyramid[T], error) {
+	return detectPeakPyramid[T](samples, func(int) bool {
+		return false
+	})
+}
+
+// Detects the levels, one after another, until either the last level has
+// been detected, as decided by the function, or a level has no peaks.
+func detectPeakPyramid[T Number](samples []T, last func(level int) bool) (PeakPyramid[T], error) {
+	primary, err := DetectPeaksE[T](samples)
+	if err != nil {
+		return PeakPyramid[T]{}, err
+	}
+	pyramid := PeakPyramid[T]{primary: primary}
+	if primary.GetPeakCount() == 0 || last(1) {
+		return pyramid, nil
+	}
+	secondary, err := DetectPeaksInPrimaryE[T](primary)
+	for {
+		if err != nil {
+			return PeakPyramid[T]{}, err
+		}
+		if secondary.GetPeakCount() == 0 {
+			return pyramid, nil
+		}
+		pyramid.secondary = append(pyramid.secondary, secondary)
+		if last(secondary.level) {
+			return pyramid, nil
+		}
+		secondary, err = DetectPeaksInSecondaryE[T](secondary)
+	}
+}
+
+// GetLevelCount returns the number of levels in the pyramid, which is 0
+// only if the pyramid is empty, i.e., if its detection failed.
+func (p *PeakPyramid[T]) GetLevelCount() int {
+	if p.primary.level == 0 {
+		return 0
+	}
+	return 1 + len(p.secondary)
+}
+
+// GetSamples returns the original samples, in which the peaks were detected.
+func (p *PeakPyramid[T]) GetSamples() []T {
+	return p.primary.samples
+}
+
+func (p *PeakPyramid[T]) GetPrimary() PrimaryPeaks[T] {
+	return p.primary
+}
+
+// GetSecondary returns the peaks at the specified level, which must be one
+// of the levels above the primary level, i.e., from 2 to GetLevelCount.
+func (p *PeakPyramid[T]) GetSecondary(level int) SecondaryPeaks[T] {
+	return p.secondary[level-2]
+}
+
+func (p *PeakPyramid[T]) GetPeakCount(level int) int {
+	return len(p.GetPeaks(level))
+}
+
+// GetPeaks returns the indices of the peaks at the specified level within
+// the original samples. The level must be from 1 to GetLevelCount.
+func (p *PeakPyramid[T]) GetPeaks(level int) []int {
+	if level == 1 {
+		return p.primary.peaks
+	}
+	return p.secondary[level-2].primaryPeaks
+}
+
+// GetValues returns the values of the peaks at the specified level, in the
+// same order as their indices, as returned by GetPeaks.
+func (p *PeakPyramid[T]) GetValues(level int) []T {
+	peaks := p.GetPeaks(level)
+	values := make([]T, len(peaks))
+	for i, at := range peaks {
+		values[i] = p.primary.samples[at]
+	}
+	return values
+}
+
+// Inflate returns an array of the same length as the original samples,
+// wherein the peaks at the specified level are set to their values, and
+// all the other samples are set to zero.
+func (p *PeakPyramid[T]) Inflate(level int) []T {
+	inflated := make([]T, len(p.primary.samples))
+	for _, at := range p.GetPeaks(level) {
+		inflated[at] = p.primary.samples[at]
+	}
+	return inflated
+}
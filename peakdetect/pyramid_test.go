@@ -0,0 +1,121 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+func TestDetectPeakPyramid(t *testing.T) {
+	samples := []int{1, 8, 3, 5, 7, 2, 3, 6, 9, 0, 1, 3, 12, 1, 4, 8, 2, 4, 1, 9}
+	peaks := [][]int{{1, 4, 8, 12, 15, 17, 19}, {1, 12, 19}, {12}}
+	values := [][]int{{8, 7, 9, 12, 8, 4, 9}, {8, 12, 9}, {12}}
+	tests := []struct {
+		name    string
+		pyramid PeakPyramid[int]
+		levels  int
+	}{
+		{"no levels", DetectPeakPyramid(0, samples), 1},
+		{"primary", DetectPeakPyramid(1, samples), 1},
+		{"secondary", DetectPeakPyramid(2, samples), 2},
+		{"tertiary", DetectPeakPyramid(3, samples), 3},
+		{"beyond", DetectPeakPyramid(10, samples), 3},
+		{"completion", DetectPeakPyramidToCompletion(samples), 3},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := test.pyramid
+			if p.GetLevelCount() != test.levels {
+				t.Fatalf("expected %d levels, got %d", test.levels, p.GetLevelCount())
+			}
+			for level := 1; level <= test.levels; level++ {
+				if !slices.Equal(peaks[level-1], p.GetPeaks(level)) {
+					t.Errorf("level %d: expected peaks %v, got %v", level, peaks[level-1], p.GetPeaks(level))
+				}
+				if !slices.Equal(values[level-1], p.GetValues(level)) {
+					t.Errorf("level %d: expected values %v, got %v", level, values[level-1], p.GetValues(level))
+				}
+				if p.GetPeakCount(level) != len(peaks[level-1]) {
+					t.Errorf("level %d: expected %d peaks, got %d", level, len(peaks[level-1]), p.GetPeakCount(level))
+				}
+				inflated := p.Inflate(level)
+				for i, value := range inflated {
+					expected := 0
+					if slices.Contains(peaks[level-1], i) {
+						expected = samples[i]
+					}
+					if value != expected {
+						t.Errorf("level %d: expected %d at %d, got %d", level, expected, i, value)
+					}
+				}
+			}
+			primary := p.GetPrimary()
+			if primary.GetLevel() != 1 || !slices.Equal(peaks[0], primary.GetPeaks()) {
+				t.Errorf("unexpected primary peaks %v, at level %d", primary.GetPeaks(), primary.GetLevel())
+			}
+			for level := 2; level <= test.levels; level++ {
+				secondary := p.GetSecondary(level)
+				if secondary.GetLevel() != level || !slices.Equal(peaks[level-1], secondary.GetPrimaryPeaks()) {
+					t.Errorf("unexpected secondary peaks %v, at level %d", secondary.GetPrimaryPeaks(), secondary.GetLevel())
+				}
+				if err := Validate[int](&secondary); err != nil {
+					t.Error(err)
+				}
+			}
+		})
+	}
+}
+
+func TestDetectPeakPyramidWithoutPeaks(t *testing.T) {
+	for _, samples := range [][]int{{}, {1}, {2, 2, 2}} {
+		p := DetectPeakPyramidToCompletion(samples)
+		if p.GetLevelCount() != 1 || p.GetPeakCount(1) != 0 {
+			t.Errorf("%v: expected a primary level without peaks, got %d levels", samples, p.GetLevelCount())
+		}
+	}
+}
+
+func TestDetectPeakPyramidNaN(t *testing.T) {
+	p, err := DetectPeakPyramidE(2, []float64{1, 2, math.NaN()})
+	if !errors.Is(err, ErrNaNSample) {
+		t.Errorf("expected %v, got %v", ErrNaNSample, err)
+	}
+	if p.GetLevelCount() != 0 {
+		t.Errorf("expected an empty pyramid, got %d levels", p.GetLevelCount())
+	}
+}
+
+// Every level of the pyramid is the same as the level that
+// IteratePeakDetectToCompletion goes through.
+func TestDetectPeakPyramidLevels(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 100; n++ {
+		samples := make([]int, r.Intn(200))
+		for i := range samples {
+			samples[i] = r.Intn(10)
+		}
+		var expected [][]int
+		if _, err := iteratePeakDetectToCompletion(samples, func(level int, peaks []int) {
+			if level == 1 || len(peaks) > 0 {
+				expected = append(expected, peaks)
+			}
+		}, nil); err != nil {
+			t.Fatal(err)
+		}
+		p := DetectPeakPyramidToCompletion(samples)
+		if p.GetLevelCount() != len(expected) {
+			t.Fatalf("%v: expected %d levels, got %d", samples, len(expected), p.GetLevelCount())
+		}
+		for level := 1; level <= len(expected); level++ {
+			if !slices.Equal(expected[level-1], p.GetPeaks(level)) {
+				t.Errorf("%v: level %d: expected %v, got %v", samples, level, expected[level-1], p.GetPeaks(level))
+			}
+		}
+	}
+}
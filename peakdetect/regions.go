@@ -0,0 +1,103 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+// Peak is a single peak, which may extend across a plateau of several
+// contiguous samples, all of the same value. All the indices are those
+// of the original samples, irrespective of the level of the peak.
+type Peak[T Number] struct {
+	// Start and End are the indices of the first and the last sample
+	// of the peak, inclusive, such that a peak of a single sample
+	// starts and ends at the same index.
+	Start int
+	End   int
+	// Apex is the index of the middle sample of the peak.
+	Apex  int
+	Value T
+	Level int
+	// LeftBase and RightBase are the indices at which the slopes leading up
+	// to the peak, and down from it, end. A flat stretch of samples within
+	// the slope is part of the slope, but one at its foot is not.
+	LeftBase  int
+	RightBase int
+	// Prominence is that of the apex, see Prominences, i.e., the same
+	// for all the samples of the peak, if they are contiguous.
+	Prominence T
+}
+
+// GetPeakRegions returns the peaks, such that a plateau of several
+// contiguous peak samples is reported as a single peak.
+func (p *PrimaryPeaks[T]) GetPeakRegions() []Peak[T] {
+	return peakRegions[T](p.samples, p.peaks, p.samples, p.peaks, p.level)
+}
+
+// GetPeakRegions returns the peaks, such that a plateau of several
+// contiguous peak samples is reported as a single peak. Unlike GetPeaks,
+// the indices are those of the primary samples, rather than those of
+// the samples of this level.
+//
+// At this level, a plateau is made up of peaks of the same value, which
+// are contiguous at this level, but not necessarily in the primary
+// samples, therefore, a plateau spans all the samples between them.
+func (p *SecondaryPeaks[T]) GetPeakRegions() []Peak[T] {
+	return peakRegions[T](p.samples, p.peaks, p.primarySamples, p.primaryPeaks, p.level)
+}
+
+// Groups the peaks of a level into regions. The 'samples' and the 'peaks' are
+// those of the level, while the 'primarySamples' and the 'primaryPeaks' are
+// the original samples, and the indices of the peaks within them.
+func peakRegions[T Number](samples []T, peaks []int, primarySamples []T, primaryPeaks []int, level int) []Peak[T] {
+	regions := make([]Peak[T], 0)
+	apexes := make([]int, 0)
+	for i := 0; i < len(peaks); {
+		j := i + 1
+		for j < len(peaks) && peaks[j]-peaks[j-1] == 1 && samples[peaks[j]] == samples[peaks[i]] {
+			j++
+		}
+		start := primaryPeaks[i]
+		end := primaryPeaks[j-1]
+		regions = append(regions, Peak[T]{
+			Start:     start,
+			End:       end,
+			Apex:      primaryPeaks[i+(j-i)/2],
+			Value:     samples[peaks[i]],
+			Level:     level,
+			LeftBase:  leftBase[T](start, primarySamples),
+			RightBase: rightBase[T](end, primarySamples),
+		})
+		apexes = append(apexes, regions[len(regions)-1].Apex)
+		i = j
+	}
+	for i, prominence := range Prominences[T](primarySamples, apexes) {
+		regions[i].Prominence = prominence
+	}
+	return regions
+}
+
+// We descend to the left of the sample at index 'at', for as long as the
+// samples are not increasing, and return the index of the last sample at
+// which they were still decreasing.
+func leftBase[T Number](at int, samples []T) int {
+	base := at
+	for i := at; i > 0 && samples[i-1] <= samples[i]; i-- {
+		if samples[i-1] < samples[i] {
+			base = i - 1
+		}
+	}
+	return base
+}
+
+// We descend to the right of the sample at index 'at', for as long as the
+// samples are not increasing, and return the index of the last sample at
+// which they were still decreasing.
+func rightBase[T Number](at int, samples []T) int {
+	base := at
+	for i := at; i < len(samples)-1 && samples[i+1] <= samples[i]; i++ {
+		if samples[i+1] < samples[i] {
+			base = i + 1
+		}
+	}
+	return base
+}
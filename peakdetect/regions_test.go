@@ -0,0 +1,107 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestGetPeakRegions(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples []int
+		regions []Peak[int]
+	}{
+		{"none", []int{2, 2, 2}, []Peak[int]{}},
+		{
+			"first and last samples",
+			[]int{3, 1, 2},
+			[]Peak[int]{
+				{Start: 0, End: 0, Apex: 0, Value: 3, Level: 1, LeftBase: 0, RightBase: 1, Prominence: 2},
+				{Start: 2, End: 2, Apex: 2, Value: 2, Level: 1, LeftBase: 1, RightBase: 2, Prominence: 1},
+			},
+		},
+		{
+			"plateaus at the edges",
+			[]int{5, 5, 1, 3, 3},
+			[]Peak[int]{
+				{Start: 0, End: 1, Apex: 1, Value: 5, Level: 1, LeftBase: 0, RightBase: 2, Prominence: 4},
+				{Start: 3, End: 4, Apex: 4, Value: 3, Level: 1, LeftBase: 2, RightBase: 4, Prominence: 2},
+			},
+		},
+		{
+			"plateau",
+			[]int{0, 2, 2, 2, 0},
+			[]Peak[int]{
+				{Start: 1, End: 3, Apex: 2, Value: 2, Level: 1, LeftBase: 0, RightBase: 4, Prominence: 2},
+			},
+		},
+		// A flat stretch within a slope is part of the slope, one at its foot is not.
+		{
+			"flat slopes",
+			[]int{0, 1, 1, 2, 4, 2, 2, 1, 1},
+			[]Peak[int]{
+				{Start: 4, End: 4, Apex: 4, Value: 4, Level: 1, LeftBase: 0, RightBase: 7, Prominence: 4},
+			},
+		},
+		{
+			"plateau of an even length",
+			[]int{0, 3, 3, 0},
+			[]Peak[int]{
+				{Start: 1, End: 2, Apex: 2, Value: 3, Level: 1, LeftBase: 0, RightBase: 3, Prominence: 3},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			primary := DetectPeaks(test.samples)
+			if regions := primary.GetPeakRegions(); !slices.Equal(test.regions, regions) {
+				t.Errorf("expected %v, got %v", test.regions, regions)
+			}
+		})
+	}
+}
+
+// At the secondary levels, a plateau is made up of peaks that are contiguous
+// at the level, and spans all the primary samples between them.
+func TestGetPeakRegionsSecondary(t *testing.T) {
+	samples := []int{0, 5, 1, 5, 0, 3, 0}
+	secondary, _ := IteratePeakDetect(1, samples)
+	expected := []Peak[int]{
+		{Start: 1, End: 3, Apex: 3, Value: 5, Level: 2, LeftBase: 0, RightBase: 4, Prominence: 5},
+	}
+	if regions := secondary.GetPeakRegions(); !slices.Equal(expected, regions) {
+		t.Errorf("expected %v, got %v", expected, regions)
+	}
+}
+
+// Every region covers exactly the peaks it groups, and lies between its bases.
+func TestGetPeakRegionsBounds(t *testing.T) {
+	for numberOfPlaces := 1; numberOfPlaces <= 7; numberOfPlaces++ {
+		t.Run(fmt.Sprintf("places=%d", numberOfPlaces), func(t *testing.T) {
+			for _, samples := range product(t, []int{0, 1, 2}, numberOfPlaces) {
+				primary := DetectPeaks(samples)
+				covered := make([]int, 0)
+				for _, region := range primary.GetPeakRegions() {
+					if !(0 <= region.LeftBase && region.LeftBase <= region.Start && region.Start <= region.Apex &&
+						region.Apex <= region.End && region.End <= region.RightBase && region.RightBase < len(samples)) {
+						t.Fatalf("samples %v: region out of bounds %+v", samples, region)
+					}
+					for at := region.Start; at <= region.End; at++ {
+						if samples[at] != region.Value {
+							t.Fatalf("samples %v: sample %d is not of region %+v", samples, at, region)
+						}
+						covered = append(covered, at)
+					}
+				}
+				if !slices.Equal(primary.GetPeaks(), covered) {
+					t.Fatalf("samples %v: expected the regions to cover %v, got %v", samples, primary.GetPeaks(), covered)
+				}
+			}
+		})
+	}
+}
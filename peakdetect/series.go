@@ -0,0 +1,108 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import (
+	"fmt"
+	"time"
+)
+
+// Series is a series of samples, along with the time at which each of them
+// was taken. The samples need not be taken at regular intervals, since the
+// peaks are detected irrespective of the time between the samples.
+type Series[T Number] struct {
+	Timestamps []time.Time
+	Values     []T
+}
+
+// SeriesPeaks are the peaks detected at one level of the hierarchy. Each peak
+// is described by its index within the original series, along with its
+// timestamp, value, and prominence, see Prominences, such that all the slices
+// are of the same length.
+type SeriesPeaks[T Number] struct {
+	Level       int
+	Indices     []int
+	Timestamps  []time.Time
+	Values      []T
+	Prominences []T
+}
+
+func CreateSeries[T Number](timestamps []time.Time, values []T) Series[T] {
+	return Series[T]{timestamps, values}
+}
+
+func DetectPeaksSeries[T Number](s Series[T]) (SeriesPeaks[T], error) {
+	if err := s.validate(); err != nil {
+		return SeriesPeaks[T]{}, err
+	}
+	primary, err := DetectPeaksE[T](s.Values)
+	if err != nil {
+		return SeriesPeaks[T]{}, err
+	}
+	return s.peaksAt(primary.level, primary.peaks, Prominences[T](s.Values, primary.peaks)), nil
+}
+
+// IteratePeakDetectSeries returns the peaks at the level IteratePeakDetect
+// detects with the same number of iterations. Use IteratePeakDetectSeriesLevels
+// for the peaks at all the levels up to it.
+func IteratePeakDetectSeries[T Number](iterations uint, s Series[T]) (SeriesPeaks[T], bool, error) {
+	levels, ok, err := IteratePeakDetectSeriesLevels[T](iterations, s)
+	if err != nil || !ok {
+		return SeriesPeaks[T]{}, ok, err
+	}
+	return levels[len(levels)-1], true, nil
+}
+
+// IteratePeakDetectSeriesLevels returns the peaks at every level, starting
+// with the primary peaks at level 1, up to, and including, the level that
+// IteratePeakDetect detects with the same number of iterations, each with
+// the timestamps of its peaks. The last level is the first one without any
+// peaks, if the detection stops there.
+func IteratePeakDetectSeriesLevels[T Number](iterations uint, s Series[T]) ([]SeriesPeaks[T], bool, error) {
+	if err := s.validate(); err != nil {
+		return nil, false, err
+	}
+	if iterations == 0 {
+		return nil, false, nil
+	}
+	levels, err := detectLevels[T](max(iterations, 2), s.Values)
+	if err != nil {
+		return nil, false, err
+	}
+	peaks := make([]SeriesPeaks[T], max(len(levels), 2))
+	for i := range peaks {
+		var indices []int
+		if i < len(levels) {
+			indices = levels[i]
+		}
+		peaks[i] = s.peaksAt(i+1, indices, Prominences[T](s.Values, indices))
+	}
+	return peaks, true, nil
+}
+
+func (s Series[T]) validate() error {
+	if len(s.Timestamps) != len(s.Values) {
+		return fmt.Errorf("%w: %d timestamps for %d values", ErrInvalidSampleCount, len(s.Timestamps), len(s.Values))
+	}
+	return nil
+}
+
+// Describes the peaks, given their indices within the original series,
+// and their prominences.
+func (s Series[T]) peaksAt(level int, indices []int, prominences []T) SeriesPeaks[T] {
+	peaks := SeriesPeaks[T]{
+		Level:       level,
+		Indices:     make([]int, len(indices)),
+		Timestamps:  make([]time.Time, len(indices)),
+		Values:      make([]T, len(indices)),
+		Prominences: prominences,
+	}
+	for i, at := range indices {
+		peaks.Indices[i] = at
+		peaks.Timestamps[i] = s.Timestamps[at]
+		peaks.Values[i] = s.Values[at]
+	}
+	return peaks
+}
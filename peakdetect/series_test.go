@@ -0,0 +1,100 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// Irregularly sampled, such that no timestamp can be computed from an index.
+func irregularSeries(values []int) Series[int] {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	timestamps := make([]time.Time, len(values))
+	for i := range values {
+		timestamps[i] = start.Add(time.Duration(i*i) * time.Second)
+	}
+	return CreateSeries(timestamps, values)
+}
+
+func TestDetectPeaksSeries(t *testing.T) {
+	s := irregularSeries([]int{1, 3, 2, 2, 5, 5, 4})
+	peaks, err := DetectPeaksSeries(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectSeriesPeaks(t, s, 1, []int{1, 4, 5}, peaks)
+}
+
+// At every level, the peaks carry the timestamps of the original samples,
+// rather than those of the samples at the indices within the level.
+func TestIteratePeakDetectSeriesLevels(t *testing.T) {
+	s := irregularSeries([]int{1, 8, 3, 5, 7, 2, 3, 6, 9, 0, 1, 3, 12, 1, 4, 8, 2, 4, 1, 9})
+	levels, ok, err := IteratePeakDetectSeriesLevels(4, s)
+	if err != nil || !ok {
+		t.Fatalf("expected the levels, got %v", err)
+	}
+	expected := [][]int{{1, 4, 8, 12, 15, 17, 19}, {1, 12, 19}, {12}, {}}
+	if len(levels) != len(expected) {
+		t.Fatalf("expected %d levels, got %d", len(expected), len(levels))
+	}
+	for i, peaks := range levels {
+		expectSeriesPeaks(t, s, i+1, expected[i], peaks)
+	}
+
+	// The levels are those IteratePeakDetect goes through.
+	for iterations := uint(1); iterations <= 6; iterations++ {
+		levels, _, _ := IteratePeakDetectSeriesLevels(iterations, s)
+		last, _, _ := IteratePeakDetectSeries(iterations, s)
+		secondary, _ := IteratePeakDetect(iterations, s.Values)
+		if n := len(levels); levels[n-1].Level != secondary.GetLevel() || n != secondary.GetLevel() {
+			t.Errorf("%d iterations: expected %d levels, got %d", iterations, secondary.GetLevel(), n)
+		}
+		expectSeriesPeaks(t, s, secondary.GetLevel(), secondary.GetPrimaryPeaks(), last)
+	}
+	if _, ok, _ := IteratePeakDetectSeriesLevels(0, s); ok {
+		t.Error("expected no iterations")
+	}
+}
+
+func expectSeriesPeaks(t *testing.T, s Series[int], level int, indices []int, peaks SeriesPeaks[int]) {
+	t.Helper()
+	if peaks.Level != level || !slices.Equal(indices, peaks.Indices) {
+		t.Fatalf("expected %v at level %d, got %v at level %d", indices, level, peaks.Indices, peaks.Level)
+	}
+	if len(peaks.Timestamps) != len(indices) || len(peaks.Values) != len(indices) || len(peaks.Prominences) != len(indices) {
+		t.Fatalf("level %d: expected %d timestamps, values, and prominences, got %v", level, len(indices), peaks)
+	}
+	for i, at := range indices {
+		if !peaks.Timestamps[i].Equal(s.Timestamps[at]) || peaks.Values[i] != s.Values[at] {
+			t.Errorf("level %d: expected peak %d at %v, of %d, got %v, of %d",
+				level, at, s.Timestamps[at], s.Values[at], peaks.Timestamps[i], peaks.Values[i])
+		}
+	}
+}
+
+func TestSeriesLengths(t *testing.T) {
+	s := irregularSeries([]int{1, 3, 2})
+	for _, mismatched := range []Series[int]{
+		CreateSeries(s.Timestamps[:2], s.Values),
+		CreateSeries(s.Timestamps, s.Values[:2]),
+		CreateSeries(nil, s.Values),
+	} {
+		if _, err := DetectPeaksSeries(mismatched); !errors.Is(err, ErrInvalidSampleCount) {
+			t.Errorf("expected %v, got %v", ErrInvalidSampleCount, err)
+		}
+		if _, _, err := IteratePeakDetectSeries(2, mismatched); !errors.Is(err, ErrInvalidSampleCount) {
+			t.Errorf("expected %v, got %v", ErrInvalidSampleCount, err)
+		}
+		if _, _, err := IteratePeakDetectSeriesLevels(2, mismatched); !errors.Is(err, ErrInvalidSampleCount) {
+			t.Errorf("expected %v, got %v", ErrInvalidSampleCount, err)
+		}
+	}
+	if peaks, err := DetectPeaksSeries(Series[int]{}); err != nil || len(peaks.Indices) != 0 {
+		t.Errorf("expected no peaks, got %v, %v", peaks, err)
+	}
+}
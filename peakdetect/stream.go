@@ -0,0 +1,137 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Detector detects the primary peaks in a stream of samples, which are
+// pushed into it one at a time. It reports the same peaks as DetectPeaks
+// would, had it been given all the samples at once, but it reports each
+// peak as soon as the peak becomes final.
+//
+// Each sample is merged into the samples that are still pending as a cluster
+// of its own, the same way DetectPeaks merges the one or two samples that are
+// left over after the last cluster of 3 samples. Only the samples of the last
+// plateau can change whether they are peaks when the next sample is merged,
+// and all the samples before them are final. The merge only looks at whether
+// the samples on either side of the boundary are equal, and are peaks, and at
+// the contiguous samples equal to them. Therefore, the plateau is kept as its
+// value, and its length, and only ever merged as one or two of its samples,
+// along with the sample before it, such that pushing a sample takes the same
+// time, and memory, however long the plateau, or the stream, is.
+//
+// The zero value is ready to use.
+type Detector[T Number] struct {
+	// The value of the samples of the last plateau, the index within the
+	// stream of its first sample, and the number of its samples, which is
+	// zero before the first sample is pushed.
+	value  T
+	start  int
+	length int
+	// Whether the last plateau is preceded by another sample, and its value.
+	preceded bool
+	previous T
+	// Whether the samples of the last plateau are peaks, as of the samples
+	// pushed so far.
+	peak bool
+}
+
+func CreateDetector[T Number]() *Detector[T] {
+	return &Detector[T]{}
+}
+
+// Push adds the next sample of the stream, and returns the indices within
+// the stream of the peaks that have become final, if any. A NaN is not added.
+// Use PushE to find out why.
+func (d *Detector[T]) Push(sample T) []int {
+	final, _ := d.PushE(sample)
+	return final
+}
+
+// PushE rejects a NaN, which cannot be ordered, and therefore, would fail the
+// detection of the peaks in any stream that contains it, leaving the detector
+// as it was.
+func (d *Detector[T]) PushE(sample T) ([]int, error) {
+	// Only a NaN is not equal to itself.
+	if sample != sample {
+		return nil, fmt.Errorf("%w: sample %d", ErrNaNSample, d.start+d.length)
+	}
+	if d.length == 0 {
+		d.value, d.length = sample, 1
+		return nil, nil
+	}
+	left, first := d.pending()
+	// The merge only fails if neither side has any samples,
+	// and the single sample on the 'right' always has one.
+	merged, _ := merge[T](left, peakDetectSample[T](sample))
+	if sample == d.value {
+		d.length++
+		d.peak = merged.isLastSamplePeak()
+		return nil, nil
+	}
+
+	// The plateau ends, and whether it is a peak is final.
+	var final []int
+	if slices.Contains(merged.peaks, first) {
+		final = d.plateau()
+	}
+	d.preceded, d.previous = true, d.value
+	d.value, d.start, d.length = sample, d.start+d.length, 1
+	d.peak = merged.isLastSamplePeak()
+	return final, nil
+}
+
+// Flush ends the stream, and returns the indices within the stream of the
+// peaks that were not yet final. The detector is then reset, so that the
+// next sample pushed is taken to be the first sample of a new stream.
+func (d *Detector[T]) Flush() []int {
+	var final []int
+	if d.peak {
+		final = d.plateau()
+	}
+	*d = Detector[T]{}
+	return final
+}
+
+// Returns the pending samples, i.e., the sample before the plateau, if any,
+// followed by at most two samples of the plateau, along with the index of
+// the first of the samples of the plateau among them.
+//
+// If the sample before the plateau is greater than the plateau, then, if it
+// is not a peak itself, there must be a peak before it, since the stream is
+// not all of the same value. We mark it as a peak, so that the merge finds
+// there are peaks on the 'left', as there would be, had we kept all the
+// samples, rather than take the plateau to be the only samples there are,
+// and mark it as a peak.
+func (d *Detector[T]) pending() (PrimaryPeaks[T], int) {
+	samples := make([]T, 0, 4)
+	peaks := make([]int, 0, 4)
+	if d.preceded {
+		if d.previous > d.value {
+			peaks = append(peaks, 0)
+		}
+		samples = append(samples, d.previous)
+	}
+	first := len(samples)
+	for i := 0; i < min(d.length, 2); i++ {
+		if d.peak {
+			peaks = append(peaks, len(samples))
+		}
+		samples = append(samples, d.value)
+	}
+	return CreatePeaksWith[T](samples, peaks), first
+}
+
+// Returns the indices within the stream of the samples of the last plateau.
+func (d *Detector[T]) plateau() []int {
+	indices := make([]int, d.length)
+	for i := range indices {
+		indices[i] = d.start + i
+	}
+	return indices
+}
@@ -0,0 +1,130 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDetector(t *testing.T) {
+	d := CreateDetector[int]()
+	tests := []struct {
+		sample int
+		final  []int
+	}{
+		{1, nil},
+		{3, nil},
+		{3, nil},
+		{2, []int{1, 2}},
+		{5, nil},
+		{5, nil},
+		{5, nil},
+		{6, nil},
+		{1, []int{7}},
+		{1, nil},
+	}
+	for _, test := range tests {
+		if final := d.Push(test.sample); !slices.Equal(test.final, final) {
+			t.Errorf("push %d: expected %v, got %v", test.sample, test.final, final)
+		}
+	}
+	if final := d.Flush(); len(final) != 0 {
+		t.Errorf("expected no more peaks, got %v", final)
+	}
+	// The stream ends on the rise, and the detector starts over.
+	d.Push(1)
+	d.Push(2)
+	d.Push(2)
+	if final := d.Flush(); !slices.Equal([]int{1, 2}, final) {
+		t.Errorf("expected [1 2], got %v", final)
+	}
+}
+
+func TestDetectorNaN(t *testing.T) {
+	d := CreateDetector[float64]()
+	d.Push(1)
+	d.Push(3)
+	d.Push(3)
+	if final, err := d.PushE(math.NaN()); !errors.Is(err, ErrNaNSample) || final != nil {
+		t.Errorf("expected %v, got %v, %v", ErrNaNSample, final, err)
+	}
+	if final := d.Push(math.NaN()); final != nil {
+		t.Errorf("expected no peaks, got %v", final)
+	}
+	// The detector is as it was before the NaN samples.
+	if final := d.Push(2); !slices.Equal([]int{1, 2}, final) {
+		t.Errorf("expected [1 2], got %v", final)
+	}
+	d.Push(1)
+	if final := d.Flush(); len(final) != 0 {
+		t.Errorf("expected no more peaks, got %v", final)
+	}
+}
+
+// Every prefix of the stream has the same peaks as DetectPeaks finds in it,
+// i.e., the peaks that have become final, along with those that a flush of
+// the detector would report at that point.
+func TestDetectorPrefixes(t *testing.T) {
+	for numberOfPlaces := 1; numberOfPlaces <= 8; numberOfPlaces++ {
+		t.Run(fmt.Sprintf("places=%d", numberOfPlaces), func(t *testing.T) {
+			for _, samples := range product(t, []int{0, 1, 2}, numberOfPlaces) {
+				expectPrefixes(t, samples)
+			}
+		})
+	}
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 100; n++ {
+		// Long plateaus, at every height, and at either end.
+		samples := make([]int, 0)
+		for len(samples) < 1000 {
+			value, length := r.Intn(4), 1+r.Intn(200)
+			for i := 0; i < length; i++ {
+				samples = append(samples, value)
+			}
+		}
+		expectPrefixes(t, samples)
+	}
+}
+
+func expectPrefixes(t *testing.T, samples []int) {
+	t.Helper()
+	d := CreateDetector[int]()
+	reported := make([]int, 0)
+	for i, sample := range samples {
+		reported = append(reported, d.Push(sample)...)
+		flushed := *d
+		peaks := append(slices.Clone(reported), flushed.Flush()...)
+		if expected := DetectPeaks(samples[:i+1]); !slices.Equal(expected.GetPeaks(), peaks) {
+			t.Fatalf("%v: expected %v, got %v", samples[:i+1], expected.GetPeaks(), peaks)
+		}
+	}
+}
+
+// Pushing a sample takes the same time however long the plateau is, such
+// that a stream that stays flat does not get any slower. A million samples
+// take a few milliseconds, rather than the hours they would, were each push
+// to take time in proportion to the length of the plateau.
+func TestDetectorPlateau(t *testing.T) {
+	d := CreateDetector[float64]()
+	start := time.Now()
+	d.Push(0)
+	for i := 0; i < 1_000_000; i++ {
+		if final := d.Push(1); len(final) != 0 {
+			t.Fatalf("expected no peaks, got %d", len(final))
+		}
+	}
+	if final := d.Push(0); len(final) != 1_000_000 || final[0] != 1 || final[len(final)-1] != 1_000_000 {
+		t.Fatalf("expected the plateau to be a peak, got %d peaks", len(final))
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the pushes to take constant time, took %v", elapsed)
+	}
+}
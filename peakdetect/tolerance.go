@@ -0,0 +1,158 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import (
+	"fmt"
+	"golang.org/x/exp/constraints"
+	"math"
+	"slices"
+)
+
+// Floating point samples that jitter in their last few bits never form
+// plateaus, since the samples of a plateau must be exactly equal, and produce
+// spurious pairs of peaks instead. A tolerance lets samples that are close
+// enough be taken to be equal.
+//
+// However, being close enough is not transitive, e.g., with an absolute
+// tolerance of 1, 0 is close to 0.8, and 0.8 is close to 1.6, but 0 is not
+// close to 1.6. The truth table of the 3 sample clusters, and the merge,
+// rely on equality being transitive, e.g., the merge takes a plateau that
+// extends across the boundary to be made up of the samples equal to the one
+// on the boundary, and would otherwise detect peaks that no ordering of the
+// samples has. Therefore, rather than compare the samples with the tolerance
+// wherever they are compared, we first divide the samples into classes. We
+// go through the samples from the least to the greatest, and a sample that
+// is close enough to the one before it joins the class of that sample,
+// whereas one that is not starts the next class. We then detect the peaks in
+// the samples, each replaced with the least sample of its class, such that
+// the triple classification, the merge, and its removal of the contiguous
+// peaks on either side of the boundary, all take the samples of the same
+// class to be equal, and only those.
+//
+// Two samples that are close enough are therefore always in the same class,
+// however the other samples fall, since no sample between them can start a
+// class. Conversely, a class may span more than the tolerance, e.g., a slow
+// ramp, in steps below the tolerance, is a single plateau. Therefore, the
+// tolerance should be well above the jitter, but well below the steps of the
+// signal.
+
+// Tolerance determines when two samples are taken to be equal, namely, when
+// they differ by no more than the absolute tolerance, or by no more than the
+// relative tolerance times the greater of their magnitudes. A zero tolerance
+// takes only equal samples to be equal.
+type Tolerance struct {
+	Absolute float64
+	Relative float64
+}
+
+func (t Tolerance) validate() error {
+	if !(t.Absolute >= 0) || !(t.Relative >= 0) {
+		return fmt.Errorf("%w: %+v", ErrInvalidTolerance, t)
+	}
+	return nil
+}
+
+// Whether the sample 'b' is close enough to the sample 'a', which it is not less than.
+func (t Tolerance) close(a, b float64) bool {
+	if a == b {
+		return true
+	}
+	// An infinity is only close to itself, however great the tolerance.
+	if math.IsInf(a, 0) || math.IsInf(b, 0) {
+		return false
+	}
+	difference := b - a
+	return difference <= t.Absolute || difference <= t.Relative*max(math.Abs(a), math.Abs(b))
+}
+
+// DetectPeaksWithTolerance returns empty peaks if the tolerance is invalid, or
+// any of the samples is NaN. Use DetectPeaksWithToleranceE to find out why.
+func DetectPeaksWithTolerance[T constraints.Float](samples []T, tolerance Tolerance) PrimaryPeaks[T] {
+	peaks, _ := DetectPeaksWithToleranceE[T](samples, tolerance)
+	return peaks
+}
+
+// DetectPeaksWithToleranceE detects the peaks in the samples, taking the
+// samples within the tolerance of each other to be equal, as described above.
+// The samples of the result are the original samples, and the peaks are the
+// indices within them. Therefore, the peaks of a plateau need not be exactly
+// equal. The levels above must be detected by IteratePeakDetectWithToleranceE,
+// rather than DetectPeaksInPrimary, which would take the samples to be exact.
+func DetectPeaksWithToleranceE[T constraints.Float](samples []T, tolerance Tolerance) (PrimaryPeaks[T], error) {
+	classes, err := classify[T](samples, tolerance)
+	if err != nil {
+		return PrimaryPeaks[T]{}, err
+	}
+	primary, err := DetectPeaksE[T](classes)
+	if err != nil {
+		return PrimaryPeaks[T]{}, err
+	}
+	primary.samples = slices.Clip(samples)
+	return primary, nil
+}
+
+// IteratePeakDetectWithTolerance returns empty peaks if the tolerance is
+// invalid, or any of the samples is NaN. Use IteratePeakDetectWithToleranceE
+// to find out why.
+func IteratePeakDetectWithTolerance[T constraints.Float](iterations uint, samples []T, tolerance Tolerance) (SecondaryPeaks[T], bool) {
+	secondary, ok, _ := IteratePeakDetectWithToleranceE[T](iterations, samples, tolerance)
+	return secondary, ok
+}
+
+// IteratePeakDetectWithToleranceE is IteratePeakDetectE, with the samples
+// within the tolerance of each other taken to be equal at every level, the
+// same way DetectPeaksWithToleranceE takes them to be. The samples of the
+// result, and its primary samples, are those of the original samples.
+func IteratePeakDetectWithToleranceE[T constraints.Float](iterations uint, samples []T, tolerance Tolerance) (SecondaryPeaks[T], bool, error) {
+	classes, err := classify[T](samples, tolerance)
+	if err != nil {
+		return SecondaryPeaks[T]{}, false, err
+	}
+	secondary, ok, err := IteratePeakDetectE[T](iterations, classes)
+	if err != nil || !ok {
+		return SecondaryPeaks[T]{}, ok, err
+	}
+	levelSamples := make([]T, len(secondary.samples))
+	for i, at := range secondary.originalPeaks {
+		levelSamples[i] = samples[at]
+	}
+	secondary.samples = levelSamples
+	secondary.primarySamples = slices.Clip(samples)
+	return secondary, true, nil
+}
+
+// Replaces each of the samples with the least sample of its class.
+func classify[T constraints.Float](samples []T, tolerance Tolerance) ([]T, error) {
+	if err := tolerance.validate(); err != nil {
+		return nil, err
+	}
+	for i, sample := range samples {
+		if isNaN[T](sample) {
+			return nil, fmt.Errorf("sample %d: %w", i, ErrNaNSample)
+		}
+	}
+	order := make([]int, len(samples))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortFunc(order, func(i, j int) int {
+		if samples[i] < samples[j] {
+			return -1
+		} else if samples[i] > samples[j] {
+			return 1
+		}
+		return 0
+	})
+	classes := make([]T, len(samples))
+	for i, at := range order {
+		if i == 0 || !tolerance.close(float64(samples[order[i-1]]), float64(samples[at])) {
+			classes[at] = samples[at]
+		} else {
+			classes[at] = classes[order[i-1]]
+		}
+	}
+	return classes, nil
+}
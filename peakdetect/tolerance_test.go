@@ -0,0 +1,212 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// Every sequence of up to 6 samples, of 3 distinct values, each of which
+// jitters in every possible way, has the same peaks, at every level, as
+// the same sequence without the jitter. So does every sequence of 2 values,
+// with samples just below the jitter of either value, within the tolerance
+// of some of the jittered samples, but not of others, which are then equal
+// to the value only if the samples in between are there to link them to it.
+func TestToleranceExhaustive(t *testing.T) {
+	const jitter = 1e-12
+	tolerance := Tolerance{Absolute: 1e-9}
+	below := jitter/2 - tolerance.Absolute
+	sets := []struct {
+		values  []float64
+		offsets []float64
+	}{
+		{[]float64{100, 101, 102}, []float64{-jitter, 0, jitter}},
+		{[]float64{100, 101}, []float64{-jitter, 0, jitter, below}},
+	}
+	n := 6
+	if testing.Short() {
+		n = 5
+	}
+	for _, set := range sets {
+		// Each sample is one of the combinations of its value and its jitter.
+		k := len(set.values) * len(set.offsets)
+		for length := 0; length <= n; length++ {
+			jittered := make([]float64, length)
+			combinations := 1
+			for i := 0; i < length; i++ {
+				combinations *= k
+			}
+			for c := 0; c < combinations; c++ {
+				for i, rest := 0, c; i < length; i, rest = i+1, rest/k {
+					jittered[i] = set.values[rest%k/len(set.offsets)] + set.offsets[rest%len(set.offsets)]
+				}
+				expectTolerated(t, linked(jittered, tolerance), jittered, tolerance)
+			}
+		}
+	}
+}
+
+// Replaces each of the samples with the least sample it is linked to, through
+// any number of samples, each close enough to the next, by comparing every
+// pair of the samples, until none of them changes.
+func linked(samples []float64, tolerance Tolerance) []float64 {
+	exact := slices.Clone(samples)
+	for changed := true; changed; {
+		changed = false
+		for i := range samples {
+			for j := range samples {
+				if tolerance.close(min(samples[i], samples[j]), max(samples[i], samples[j])) && exact[j] < exact[i] {
+					exact[i] = exact[j]
+					changed = true
+				}
+			}
+		}
+	}
+	return exact
+}
+
+// Every one of the 27 triples, and of the pairs, is classified the same way,
+// whichever way its samples jitter, and so is every merge of two triples
+// into a cluster of 6 samples, which the exhaustive test covers, above, along
+// with the merge of a triple with a pair, or a single sample.
+func TestToleranceTriples(t *testing.T) {
+	const jitter = 1e-7
+	tolerance := Tolerance{Relative: 1e-6}
+	for a := 0; a < 3; a++ {
+		for b := 0; b < 3; b++ {
+			for c := 0; c < 3; c++ {
+				exact := []float64{float64(a + 1), float64(b + 1), float64(c + 1)}
+				for j := 0; j < 8; j++ {
+					jittered := slices.Clone(exact)
+					for i := range jittered {
+						if j&(1<<i) != 0 {
+							jittered[i] += jitter
+						}
+					}
+					expectTolerated(t, exact, jittered, tolerance)
+					expectTolerated(t, exact[:2], jittered[:2], tolerance)
+				}
+			}
+		}
+	}
+}
+
+func expectTolerated(t *testing.T, exact, jittered []float64, tolerance Tolerance) {
+	t.Helper()
+	primary, err := DetectPeaksWithToleranceE(jittered, tolerance)
+	if err != nil {
+		t.Fatalf("%v: %v", jittered, err)
+	}
+	if expected := DetectPeaks(exact); !slices.Equal(expected.GetPeaks(), primary.GetPeaks()) {
+		t.Fatalf("%v: expected %v, got %v", jittered, expected.GetPeaks(), primary.GetPeaks())
+	}
+	if !slices.Equal(jittered, primary.GetSamples()) || primary.GetLevel() != 1 {
+		t.Fatalf("%v: expected the original samples, at level 1, got %v, at level %d", jittered, primary.GetSamples(), primary.GetLevel())
+	}
+	for iterations := uint(1); iterations <= 3; iterations++ {
+		secondary, ok, err := IteratePeakDetectWithToleranceE(iterations, jittered, tolerance)
+		if err != nil || !ok {
+			t.Fatalf("%v: %v", jittered, err)
+		}
+		expected, _ := IteratePeakDetect(iterations, exact)
+		if expected.GetLevel() != secondary.GetLevel() || !slices.Equal(expected.GetPrimaryPeaks(), secondary.GetPrimaryPeaks()) {
+			t.Fatalf("%v: %d iterations: expected %v, at level %d, got %v, at level %d", jittered, iterations,
+				expected.GetPrimaryPeaks(), expected.GetLevel(), secondary.GetPrimaryPeaks(), secondary.GetLevel())
+		}
+		if !slices.Equal(expected.GetPeaks(), secondary.GetPeaks()) {
+			t.Fatalf("%v: %d iterations: expected peaks %v, got %v", jittered, iterations, expected.GetPeaks(), secondary.GetPeaks())
+		}
+		for i, sample := range secondary.GetSamples() {
+			if sample != jittered[secondary.originalPeaks[i]] {
+				t.Fatalf("%v: %d iterations: expected the original samples, got %v", jittered, iterations, secondary.GetSamples())
+			}
+		}
+		if !slices.Equal(jittered, secondary.GetPrimarySamples()) {
+			t.Fatalf("%v: %d iterations: expected the original primary samples, got %v", jittered, iterations, secondary.GetPrimarySamples())
+		}
+	}
+}
+
+func TestTolerance(t *testing.T) {
+	tests := []struct {
+		name      string
+		samples   []float64
+		tolerance Tolerance
+		peaks     []int
+	}{
+		{"exact", []float64{1, 1 + 1e-12, 1, 0}, Tolerance{}, []int{1}},
+		{"absolute", []float64{1, 1 + 1e-12, 1, 0}, Tolerance{Absolute: 1e-9}, []int{0, 1, 2}},
+		{"relative", []float64{1e9, 1e9 + 1, 1e9 - 1, 0}, Tolerance{Relative: 1e-8}, []int{0, 1, 2}},
+		{"relative to the greater", []float64{0, 10, 9, 0}, Tolerance{Relative: 0.1}, []int{1, 2}},
+		{"not relative to the lesser", []float64{0, 10, 8.9, 0}, Tolerance{Relative: 0.1}, []int{1}},
+		// 0.8 is close to both 0, and 1.6, but these are not close to each
+		// other. Each sample joins the class of the one before it, therefore,
+		// all three are in the same class, and there are no peaks.
+		{"classes", []float64{0, 0.8, 1.6}, Tolerance{Absolute: 1}, []int{}},
+		{"classes reversed", []float64{1.6, 0.8, 0}, Tolerance{Absolute: 1}, []int{}},
+		{"classes apart", []float64{0, 0.8, 1.9}, Tolerance{Absolute: 1}, []int{2}},
+		// The sample at 1 is close to the least samples of the plateau, but
+		// not to the greatest, which does not split the plateau.
+		{"below the jitter", []float64{1, 2.9999995, 1, 3.0000004, 3.0000006, 3.0000004, 3.0000006, 1}, Tolerance{Absolute: 1e-6}, []int{1, 3, 4, 5, 6}},
+		{"infinity", []float64{math.Inf(-1), 0, math.Inf(-1)}, Tolerance{Absolute: 1, Relative: 1}, []int{1}},
+		{"infinities", []float64{0, math.Inf(1), math.Inf(1), 0}, Tolerance{Relative: 1}, []int{1, 2}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			primary, err := DetectPeaksWithToleranceE(test.samples, test.tolerance)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(test.peaks, primary.GetPeaks()) {
+				t.Errorf("expected %v, got %v", test.peaks, primary.GetPeaks())
+			}
+			if err := Validate[float64](&primary); err != nil && test.tolerance == (Tolerance{}) {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestToleranceZero(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for n := 0; n < 100; n++ {
+		samples := make([]float32, r.Intn(100))
+		for i := range samples {
+			samples[i] = float32(r.Intn(5)) + float32(r.Intn(2))*1e-6
+		}
+		expected := DetectPeaks(samples)
+		peaks := DetectPeaksWithTolerance(samples, Tolerance{})
+		if !slices.Equal(expected.GetPeaks(), peaks.GetPeaks()) {
+			t.Fatalf("%v: expected %v, got %v", samples, expected.GetPeaks(), peaks.GetPeaks())
+		}
+	}
+}
+
+func TestToleranceErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		samples   []float64
+		tolerance Tolerance
+		err       error
+	}{
+		{"negative", []float64{1, 2, 1}, Tolerance{Absolute: -1}, ErrInvalidTolerance},
+		{"NaN tolerance", []float64{1, 2, 1}, Tolerance{Relative: math.NaN()}, ErrInvalidTolerance},
+		{"NaN sample", []float64{1, math.NaN(), 1}, Tolerance{Absolute: 1}, ErrNaNSample},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := DetectPeaksWithToleranceE(test.samples, test.tolerance); !errors.Is(err, test.err) {
+				t.Errorf("expected %v, got %v", test.err, err)
+			}
+			if _, _, err := IteratePeakDetectWithToleranceE(2, test.samples, test.tolerance); !errors.Is(err, test.err) {
+				t.Errorf("expected %v, got %v", test.err, err)
+			}
+		})
+	}
+}
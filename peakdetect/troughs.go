@@ -0,0 +1,119 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+// Troughs are the dual of peaks. Rather than duplicating the truth table of
+// the 3 sample clusters, and the merge logic, with all the comparisons
+// reversed, we mirror the samples, such that every trough becomes a peak,
+// and every peak becomes a trough. We then detect the peaks in the mirrored
+// samples, and mirror the samples of the result back to their original values.
+//
+// Because the mirroring reverses the order of the samples exactly, the
+// troughs have the very same plateau semantics as the peaks do, i.e.,
+// a trough can extend across several samples of the same value.
+
+func DetectTroughs[T Number](samples []T) PrimaryPeaks[T] {
+	troughs, _ := DetectTroughsE[T](samples)
+	return troughs
+}
+
+func DetectTroughsE[T Number](samples []T) (PrimaryPeaks[T], error) {
+	troughs, err := DetectPeaksE[T](mirrorSamples[T](samples))
+	if err != nil {
+		return PrimaryPeaks[T]{}, err
+	}
+	troughs.samples = mirrorSamples[T](troughs.samples)
+	return troughs, nil
+}
+
+func DetectTroughsInPrimary[T Number](p PrimaryPeaks[T]) SecondaryPeaks[T] {
+	troughs, _ := DetectTroughsInPrimaryE[T](p)
+	return troughs
+}
+
+func DetectTroughsInPrimaryE[T Number](p PrimaryPeaks[T]) (SecondaryPeaks[T], error) {
+	troughs, err := DetectPeaksInPrimaryE[T](CreatePeaksWith[T](mirrorSamples[T](p.samples), p.peaks))
+	if err != nil {
+		return SecondaryPeaks[T]{}, err
+	}
+	return mirrorSecondary[T](troughs), nil
+}
+
+func DetectTroughsInSecondary[T Number](p SecondaryPeaks[T]) SecondaryPeaks[T] {
+	troughs, _ := DetectTroughsInSecondaryE[T](p)
+	return troughs
+}
+
+func DetectTroughsInSecondaryE[T Number](p SecondaryPeaks[T]) (SecondaryPeaks[T], error) {
+	troughs, err := DetectPeaksInSecondaryE[T](mirrorSecondary[T](p))
+	if err != nil {
+		return SecondaryPeaks[T]{}, err
+	}
+	return mirrorSecondary[T](troughs), nil
+}
+
+func IterateTroughDetectToCompletion[T Number](samples []T) SecondaryPeaks[T] {
+	troughs, _ := IterateTroughDetectToCompletionE[T](samples)
+	return troughs
+}
+
+func IterateTroughDetectToCompletionE[T Number](samples []T) (SecondaryPeaks[T], error) {
+	troughs, err := IteratePeakDetectToCompletionE[T](mirrorSamples[T](samples))
+	if err != nil {
+		return SecondaryPeaks[T]{}, err
+	}
+	return mirrorSecondary[T](troughs), nil
+}
+
+func IterateTroughDetect[T Number](iterations uint, samples []T) (SecondaryPeaks[T], bool) {
+	troughs, ok, _ := IterateTroughDetectE[T](iterations, samples)
+	return troughs, ok
+}
+
+func IterateTroughDetectE[T Number](iterations uint, samples []T) (SecondaryPeaks[T], bool, error) {
+	troughs, ok, err := IteratePeakDetectE[T](iterations, mirrorSamples[T](samples))
+	if err != nil || !ok {
+		return troughs, ok, err
+	}
+	return mirrorSecondary[T](troughs), true, nil
+}
+
+// Mirrors both the samples of the level, and the primary samples, returning
+// a copy, such that the arrays of the peaks passed in are left untouched.
+func mirrorSecondary[T Number](p SecondaryPeaks[T]) SecondaryPeaks[T] {
+	p.samples = mirrorSamples[T](p.samples)
+	p.primarySamples = mirrorSamples[T](p.primarySamples)
+	return p
+}
+
+func mirrorSamples[T Number](samples []T) []T {
+	if samples == nil {
+		return nil
+	}
+	mirrored := make([]T, len(samples))
+	for i, sample := range samples {
+		mirrored[i] = mirror[T](sample)
+	}
+	return mirrored
+}
+
+// Reverses the order of the samples, such that for any two samples 'a' and
+// 'b', if 'a' is less than 'b', then the mirror of 'a' is greater than the
+// mirror of 'b', and equal samples remain equal. Mirroring a mirrored sample
+// produces the original sample.
+//
+// For floating point samples, this is simply the negation. For integers, the
+// negation could overflow, e.g., there is no positive counterpart to the
+// smallest signed integer, and every unsigned integer, other than zero,
+// would wrap around. Therefore, for integers, we use the one's complement,
+// i.e., '-sample-1', which maps the smallest value onto the largest,
+// and vice versa, for both signed and unsigned integers.
+func mirror[T Number](sample T) T {
+	half := 0.5
+	if T(half) != 0 {
+		return -sample
+	}
+	return -sample - 1
+}
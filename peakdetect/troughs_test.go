@@ -0,0 +1,98 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestDetectTroughs(t *testing.T) {
+	samples := []int{3, 1, 1, 2, 0, 4, 2, 5}
+	troughs := DetectTroughs(samples)
+	if expected := []int{1, 2, 4, 6}; !slices.Equal(expected, troughs.GetPeaks()) {
+		t.Errorf("expected %v, got %v", expected, troughs.GetPeaks())
+	}
+	if !slices.Equal(samples, troughs.GetSamples()) {
+		t.Errorf("expected the original samples, got %v", troughs.GetSamples())
+	}
+	secondary, ok := IterateTroughDetect(2, samples)
+	if expected := []int{4}; !ok || !slices.Equal(expected, secondary.GetPrimaryPeaks()) {
+		t.Errorf("expected %v, got %v", expected, secondary.GetPrimaryPeaks())
+	}
+	if !slices.Equal(samples, secondary.GetPrimarySamples()) {
+		t.Errorf("expected the original primary samples, got %v", secondary.GetPrimarySamples())
+	}
+}
+
+// The least, and the greatest, values of each of the kinds of numbers are
+// mirrored onto one another, rather than overflow.
+func TestDetectTroughsEdgeValues(t *testing.T) {
+	t.Run("int", func(t *testing.T) { expectEdgeTroughs[int](t, math.MinInt, math.MaxInt) })
+	t.Run("int8", func(t *testing.T) { expectEdgeTroughs[int8](t, math.MinInt8, math.MaxInt8) })
+	t.Run("int16", func(t *testing.T) { expectEdgeTroughs[int16](t, math.MinInt16, math.MaxInt16) })
+	t.Run("int32", func(t *testing.T) { expectEdgeTroughs[int32](t, math.MinInt32, math.MaxInt32) })
+	t.Run("int64", func(t *testing.T) { expectEdgeTroughs[int64](t, math.MinInt64, math.MaxInt64) })
+	t.Run("uint", func(t *testing.T) { expectEdgeTroughs[uint](t, 0, math.MaxUint) })
+	t.Run("uint8", func(t *testing.T) { expectEdgeTroughs[uint8](t, 0, math.MaxUint8) })
+	t.Run("uint16", func(t *testing.T) { expectEdgeTroughs[uint16](t, 0, math.MaxUint16) })
+	t.Run("uint32", func(t *testing.T) { expectEdgeTroughs[uint32](t, 0, math.MaxUint32) })
+	t.Run("uint64", func(t *testing.T) { expectEdgeTroughs[uint64](t, 0, math.MaxUint64) })
+	t.Run("uintptr", func(t *testing.T) { expectEdgeTroughs[uintptr](t, 0, ^uintptr(0)) })
+	t.Run("float32", func(t *testing.T) {
+		expectEdgeTroughs[float32](t, -math.MaxFloat32, math.MaxFloat32)
+		expectEdgeTroughs[float32](t, float32(math.Inf(-1)), float32(math.Inf(1)))
+	})
+	t.Run("float64", func(t *testing.T) {
+		expectEdgeTroughs[float64](t, -math.MaxFloat64, math.MaxFloat64)
+		expectEdgeTroughs[float64](t, math.Inf(-1), math.Inf(1))
+	})
+}
+
+func expectEdgeTroughs[T Number](t *testing.T, least, greatest T) {
+	t.Helper()
+	// The neighbors of the edge values, e.g., the least value plus one, must
+	// not be mistaken for them once mirrored. The greatest floats are too
+	// great to be changed by one, and the infinities by halving, either.
+	next, previous := least+1, greatest-1
+	if next == least {
+		next, previous = least/2, greatest/2
+	}
+	if next == least {
+		next, previous = 0, 0
+	}
+	tests := []struct {
+		samples []T
+		troughs []int
+	}{
+		{[]T{greatest, least, greatest}, []int{1}},
+		{[]T{least, greatest, least}, []int{0, 2}},
+		{[]T{least, least, greatest}, []int{0, 1}},
+		{[]T{next, least, greatest, previous}, []int{1, 3}},
+		{[]T{least, next, previous, greatest}, []int{0}},
+		{[]T{greatest, previous, greatest, least, next}, []int{1, 3}},
+	}
+	for _, test := range tests {
+		troughs, err := DetectTroughsE(test.samples)
+		if err != nil {
+			t.Fatalf("%v: %v", test.samples, err)
+		}
+		if !slices.Equal(test.troughs, troughs.GetPeaks()) {
+			t.Errorf("%v: expected %v, got %v", test.samples, test.troughs, troughs.GetPeaks())
+		}
+		if !slices.Equal(test.samples, troughs.GetSamples()) {
+			t.Errorf("%v: expected the original samples, got %v", test.samples, troughs.GetSamples())
+		}
+	}
+	for _, sample := range []T{least, next, previous, greatest} {
+		if mirror(mirror(sample)) != sample {
+			t.Errorf("expected %v to be mirrored back, got %v", sample, mirror(mirror(sample)))
+		}
+	}
+	if !(mirror(greatest) < mirror(previous) && mirror(next) < mirror(least)) {
+		t.Errorf("expected the order of %v, and %v, to be reversed", []T{least, next}, []T{previous, greatest})
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// StopCondition decides at which level of the peak hierarchy to stop. It is
+// given the original indices of the peaks at each of the levels detected so
+// far, starting with the primary peaks at level 1, and returns the level to
+// stop at, which may be any of those levels, or 0, to detect the next level.
+//
+// Once a level without peaks is reached, the condition is given all the
+// levels, one last time. If it still does not stop, then the last level
+// that has any peaks is chosen.
+type StopCondition func(levels [][]int) int
+
+// IteratePeakDetectUntil detects the levels of the peak hierarchy, one after
+// another, until the stop condition chooses one of them, and returns the
+// peaks at the chosen level, along with the level. If the primary level is
+// chosen, then it is returned as SecondaryPeaks, whose samples, and primary
+// samples, are the same, such that its primary peaks are its peaks.
+func IteratePeakDetectUntil[T Number](samples []T, stop StopCondition) (SecondaryPeaks[T], int, error) {
+	primary, err := DetectPeaksE[T](samples)
+	if err != nil {
+		return SecondaryPeaks[T]{}, 0, err
+	}
+	// The levels above the primary level. The primary level is only made
+	// into SecondaryPeaks if it is chosen, since that takes an index for
+	// every sample.
+	var levels []SecondaryPeaks[T]
+	chosen := func(level int) (SecondaryPeaks[T], int, error) {
+		if level == 1 {
+			return primaryAsSecondary[T](primary), 1, nil
+		}
+		return levels[level-2], level, nil
+	}
+	peaks := [][]int{primary.peaks}
+	for {
+		if level := stop(peaks); level > 0 {
+			return chosen(min(level, len(peaks)))
+		}
+		if len(peaks[len(peaks)-1]) == 0 {
+			break
+		}
+		var next SecondaryPeaks[T]
+		if len(levels) == 0 {
+			next, err = DetectPeaksInPrimaryE[T](primary)
+		} else {
+			next, err = DetectPeaksInSecondaryE[T](levels[len(levels)-1])
+		}
+		if err != nil {
+			return SecondaryPeaks[T]{}, 0, err
+		}
+		levels = append(levels, next)
+		peaks = append(peaks, next.primaryPeaks)
+	}
+	level := len(peaks)
+	for level > 1 && len(peaks[level-1]) == 0 {
+		level--
+	}
+	return chosen(level)
+}
+
+// The primary peaks, as the level of the hierarchy that they are, such that
+// the sample at each index of the level is the original sample at that index.
+func primaryAsSecondary[T Number](p PrimaryPeaks[T]) SecondaryPeaks[T] {
+	originalPeaks := make([]int, len(p.samples))
+	for i := range originalPeaks {
+		originalPeaks[i] = i
+	}
+	return SecondaryPeaks[T]{p, p.samples, p.peaks, originalPeaks}
+}
+
+// UntilPeakCount stops at the first level that has no more than the
+// specified number of peaks, but does have some.
+func UntilPeakCount(peaks int) StopCondition {
+	return func(levels [][]int) int {
+		if n := len(levels[len(levels)-1]); n > 0 && n <= peaks {
+			return len(levels)
+		}
+		return 0
+	}
+}
+
+// UntilPeaksPerSpan stops at the first level, at which no span of time of
+// the specified duration has more than the specified number of peaks in it.
+// The timestamps are those of the samples, and must be in ascending order.
+func UntilPeaksPerSpan(timestamps []time.Time, peaks int, span time.Duration) StopCondition {
+	return func(levels [][]int) int {
+		last := levels[len(levels)-1]
+		if len(last) == 0 {
+			return 0
+		}
+		// For each peak, we count the peaks in the span that ends at it.
+		for first, i := 0, 0; i < len(last); i++ {
+			for first < i && timestamps[last[i]].Sub(timestamps[last[first]]) >= span {
+				first++
+			}
+			if i-first+1 > peaks {
+				return 0
+			}
+		}
+		return len(levels)
+	}
+}
+
+// UntilElbow detects all the levels, and then stops at the elbow of the
+// curve of the number of peaks per level, i.e., at the level after which
+// the number of peaks stops dropping sharply. The elbow is the point of
+// the curve that is the farthest from the chord connecting its first and
+// last points, once both axes are scaled to the same range. If there are
+// fewer than 3 levels with peaks, then the last of them is chosen.
+func UntilElbow() StopCondition {
+	return func(levels [][]int) int {
+		if len(levels[len(levels)-1]) > 0 {
+			return 0
+		}
+		counts := make([]float64, 0, len(levels))
+		for _, peaks := range levels {
+			if len(peaks) > 0 {
+				counts = append(counts, float64(len(peaks)))
+			}
+		}
+		if len(counts) < 3 {
+			return max(len(counts), 1)
+		}
+		// The points are (x, y), where x goes from 0 at the first level, to 1
+		// at the last level, and y goes from 1 at the most peaks, to 0 at the
+		// fewest peaks, such that the chord is the line from (0, 1) to (1, 0),
+		// i.e., x + y = 1, and the distance of a point from it is proportional
+		// to 1 - x - y, for the points below it.
+		most := slices.Max(counts)
+		fewest := slices.Min(counts)
+		elbow := 0
+		farthest := math.Inf(-1)
+		for i, count := range counts {
+			x := float64(i) / float64(len(counts)-1)
+			y := 1.0
+			if most > fewest {
+				y = (count - fewest) / (most - fewest)
+			}
+			if d := 1 - x - y; d > farthest {
+				elbow = i
+				farthest = d
+			}
+		}
+		return elbow + 1
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestIteratePeakDetectUntil(t *testing.T) {
+	// The levels have 7, 3, and 1 peaks, see TestMultipass.
+	samples := []int{1, 8, 3, 5, 7, 2, 3, 6, 9, 0, 1, 3, 12, 1, 4, 8, 2, 4, 1, 9}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	timestamps := make([]time.Time, len(samples))
+	for i := range timestamps {
+		timestamps[i] = start.Add(time.Duration(i) * time.Minute)
+	}
+	tests := []struct {
+		name  string
+		stop  StopCondition
+		level int
+		peaks []int
+	}{
+		{"at most 100 peaks", UntilPeakCount(100), 1, []int{1, 4, 8, 12, 15, 17, 19}},
+		{"at most 3 peaks", UntilPeakCount(3), 2, []int{1, 12, 19}},
+		{"at most 1 peak", UntilPeakCount(1), 3, []int{12}},
+		{"no peaks", UntilPeakCount(0), 3, []int{12}},
+		{"a peak per 5 minutes", UntilPeaksPerSpan(timestamps, 1, 5*time.Minute), 2, []int{1, 12, 19}},
+		{"a peak per 8 minutes", UntilPeaksPerSpan(timestamps, 1, 8*time.Minute), 3, []int{12}},
+		{"3 peaks per 2 minutes", UntilPeaksPerSpan(timestamps, 3, 2*time.Minute), 1, []int{1, 4, 8, 12, 15, 17, 19}},
+		{"elbow", UntilElbow(), 2, []int{1, 12, 19}},
+		{"never", func([][]int) int { return 0 }, 3, []int{12}},
+		{"beyond the latest level", func([][]int) int { return 5 }, 1, []int{1, 4, 8, 12, 15, 17, 19}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			peaks, level, err := IteratePeakDetectUntil(samples, test.stop)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if level != test.level || peaks.GetLevel() != test.level {
+				t.Errorf("expected level %d, got %d, at level %d", test.level, level, peaks.GetLevel())
+			}
+			if !slices.Equal(test.peaks, peaks.GetPrimaryPeaks()) {
+				t.Errorf("expected peaks %v, got %v", test.peaks, peaks.GetPrimaryPeaks())
+			}
+			if err := Validate[int](&peaks); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestIteratePeakDetectUntilLevels(t *testing.T) {
+	samples := []int{1, 3, 2, 5, 1}
+	var calls [][][]int
+	_, level, err := IteratePeakDetectUntil(samples, func(levels [][]int) int {
+		calls = append(calls, slices.Clone(levels))
+		return 0
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if level != 2 {
+		t.Errorf("expected level 2, got %d", level)
+	}
+	// The condition is called once per level, including the last one,
+	// which has no peaks.
+	expected := [][][]int{
+		{{1, 3}},
+		{{1, 3}, {3}},
+		{{1, 3}, {3}, {}},
+	}
+	if len(calls) != len(expected) {
+		t.Fatalf("expected %d calls, got %v", len(expected), calls)
+	}
+	for i := range expected {
+		if !slices.EqualFunc(expected[i], calls[i], func(a, b []int) bool { return slices.Equal(a, b) }) {
+			t.Errorf("call %d: expected %v, got %v", i, expected[i], calls[i])
+		}
+	}
+}
+
+func TestUntilElbow(t *testing.T) {
+	levels := func(counts ...int) [][]int {
+		levels := make([][]int, len(counts))
+		for i, count := range counts {
+			levels[i] = make([]int, count)
+		}
+		return levels
+	}
+	tests := []struct {
+		counts []int
+		level  int
+	}{
+		{[]int{10, 5}, 0},
+		{[]int{0}, 1},
+		{[]int{10, 0}, 1},
+		{[]int{10, 5, 0}, 2},
+		{[]int{100, 20, 10, 5, 2, 1, 0}, 2},
+		{[]int{100, 90, 80, 10, 1, 0}, 4},
+	}
+	for _, test := range tests {
+		if level := UntilElbow()(levels(test.counts...)); level != test.level {
+			t.Errorf("%v: expected level %d, got %d", test.counts, test.level, level)
+		}
+	}
+}
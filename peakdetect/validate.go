@@ -4,59 +4,118 @@
 
 package peakdetect
 
-import "math/big"
+import "fmt"
 
-// Verifies that all peak samples are actually peaks,
-// and that all the remaining samples, are not peaks,
-func isValid[T Number](p Peaks[T]) bool {
-	var peakCount int
-	var seenPeaks big.Int
-	peaks := p.GetPeaks()
-	samples := p.GetSamples()
-	for _, peakIndex := range peaks {
-		if isPeak(peakIndex, samples, true) {
-			seenPeaks.SetBit(&seenPeaks, peakIndex, 1)
-			peakCount++
-			continue
-		}
-		return false
+// ViolationKind describes how the peaks disagree with the samples.
+type ViolationKind int
+
+const (
+	// MissedPeak is a sample that is a peak, but is not among the peaks.
+	MissedPeak ViolationKind = iota
+	// FalsePeak is a sample that is among the peaks, but is not a peak.
+	FalsePeak
+	// InvalidPeakIndex is a peak index that is outside the samples,
+	// or that does not follow the previous peak index.
+	InvalidPeakIndex
+)
+
+func (k ViolationKind) String() string {
+	switch k {
+	case MissedPeak:
+		return "missed peak"
+	case FalsePeak:
+		return "false peak"
+	case InvalidPeakIndex:
+		return "invalid peak index"
+	default:
+		return fmt.Sprintf("ViolationKind(%d)", int(k))
 	}
-	var nonPeakCount int
-	for i, _ := range samples {
-		if seenPeaks.Bit(i) == 0 && !isPeak(i, samples, false) {
-			nonPeakCount++
-		}
+}
+
+// ValidationError describes the first sample at which the peaks disagree
+// with the samples. The neighbors are the nearest samples, to the left and
+// to the right, whose value differs from that of the sample, i.e., those
+// at the foot of the plateau the sample is part of. If there is no such
+// sample on a side, the index on that side is -1.
+type ValidationError[T Number] struct {
+	Kind       ViolationKind
+	Index      int
+	Value      T
+	LeftIndex  int
+	Left       T
+	RightIndex int
+	Right      T
+}
+
+func (e *ValidationError[T]) Error() string {
+	if e.Kind == InvalidPeakIndex {
+		return fmt.Sprintf("%v %d", e.Kind, e.Index)
 	}
-	return peakCount+nonPeakCount == len(samples)
+	return fmt.Sprintf("%v at sample %d: value %v, left %s, right %s",
+		e.Kind, e.Index, e.Value, neighbor(e.LeftIndex, e.Left), neighbor(e.RightIndex, e.Right))
+}
+
+func (e *ValidationError[T]) Unwrap() error {
+	return ErrInvalidPeaks
 }
 
-// Check whether the sample located at the specified index is a peak.
+func neighbor[T Number](at int, value T) string {
+	if at < 0 {
+		return "none"
+	}
+	return fmt.Sprintf("%v at sample %d", value, at)
+}
+
+// Validate verifies that all the peak samples are actually peaks, and that
+// all the remaining samples are not, returning a *ValidationError describing
+// the first sample at which that is not the case.
 //
-// It is considered a peak, if it is greater than either one, or both
-// of its near neighbors. If one or both of its near neighbors are the
-// same value as itself, we must extend to either the left or right,
-// looking for a sample that is a different value.
-func isPeak[T Number](at int, samples []T, initialSampleIsAPeak bool) bool {
-	if at < 0 || at >= len(samples) {
-		return false
+// A sample is a peak if the plateau it is part of, i.e., the run of equal
+// samples, is greater than the samples on either side of it, where there
+// are any. If all the samples are equal, then none of them is a peak.
+func Validate[T Number](p Peaks[T]) error {
+	samples := p.GetSamples()
+	isPeak := make([]bool, len(samples))
+	previous := -1
+	for _, at := range p.GetPeaks() {
+		if at <= previous || at >= len(samples) {
+			return &ValidationError[T]{Kind: InvalidPeakIndex, Index: at, LeftIndex: -1, RightIndex: -1}
+		}
+		isPeak[at] = true
+		previous = at
 	}
-	if len(samples) == 0 {
-		return false
-	} else if len(samples) == 1 {
-		return false
-	} else /* len(samples) >= 2 */ {
-		if at == 0 {
-			return samples[at] > samples[at+1] || peakExtendsRight[T](at, samples, initialSampleIsAPeak)
-		} else if at == len(samples)-1 {
-			return samples[at-1] < samples[at] || peakExtendsLeft[T](at, samples, initialSampleIsAPeak)
-		} else /* at > 0 && < len(p.samples)-1*/ {
-			greaterThanRight := samples[at] > samples[at+1]
-			smallerThanLeft := samples[at-1] < samples[at]
-			return greaterThanRight && smallerThanLeft ||
-				greaterThanRight && peakExtendsLeft[T](at, samples, initialSampleIsAPeak) ||
-				smallerThanLeft && peakExtendsRight[T](at, samples, initialSampleIsAPeak) ||
-				peakExtendsLeft[T](at, samples, initialSampleIsAPeak) &&
-					peakExtendsRight[T](at, samples, initialSampleIsAPeak)
+	for start := 0; start < len(samples); {
+		end := start
+		for end+1 < len(samples) && samples[end+1] == samples[start] {
+			end++
+		}
+		e := ValidationError[T]{Value: samples[start], LeftIndex: start - 1, RightIndex: end + 1}
+		peak := start > 0 || end < len(samples)-1
+		if start > 0 {
+			e.Left = samples[start-1]
+			peak = peak && e.Left < e.Value
 		}
+		if end < len(samples)-1 {
+			e.Right = samples[end+1]
+			peak = peak && e.Right < e.Value
+		} else {
+			e.RightIndex = -1
+		}
+		for at := start; at <= end; at++ {
+			if isPeak[at] != peak {
+				e.Index = at
+				e.Kind = MissedPeak
+				if isPeak[at] {
+					e.Kind = FalsePeak
+				}
+				return &e
+			}
+		}
+		start = end + 1
 	}
+	return nil
+}
+
+func isValid[T Number](p Peaks[T]) bool {
+	return Validate[T](p) == nil
 }
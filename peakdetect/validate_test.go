@@ -0,0 +1,80 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples []int
+		peaks   []int
+		err     *ValidationError[int]
+	}{
+		{"no samples", []int{}, []int{}, nil},
+		{"single sample", []int{1}, []int{}, nil},
+		{"all equal", []int{2, 2, 2}, []int{}, nil},
+		{"single peak", []int{1, 3, 2}, []int{1}, nil},
+		{"plateau", []int{1, 3, 3, 2}, []int{1, 2}, nil},
+		{"boundaries", []int{3, 1, 2, 2}, []int{0, 2, 3}, nil},
+		{"missed peak", []int{1, 3, 2, 4, 1}, []int{1},
+			&ValidationError[int]{MissedPeak, 3, 4, 2, 2, 4, 1}},
+		{"missed peak on a plateau", []int{1, 3, 3, 2}, []int{1},
+			&ValidationError[int]{MissedPeak, 2, 3, 0, 1, 3, 2}},
+		{"missed peak at the end", []int{1, 2, 2}, []int{},
+			&ValidationError[int]{MissedPeak, 1, 2, 0, 1, -1, 0}},
+		{"false peak", []int{1, 3, 2}, []int{1, 2},
+			&ValidationError[int]{FalsePeak, 2, 2, 1, 3, -1, 0}},
+		{"false peak at the start", []int{1, 1, 3}, []int{0, 2},
+			&ValidationError[int]{FalsePeak, 0, 1, -1, 0, 2, 3}},
+		{"false peak when all equal", []int{2, 2}, []int{1},
+			&ValidationError[int]{FalsePeak, 1, 2, -1, 0, -1, 0}},
+		{"peak out of range", []int{1, 3, 2}, []int{1, 3},
+			&ValidationError[int]{InvalidPeakIndex, 3, 0, -1, 0, -1, 0}},
+		{"negative peak", []int{1, 3, 2}, []int{-1},
+			&ValidationError[int]{InvalidPeakIndex, -1, 0, -1, 0, -1, 0}},
+		{"duplicate peak", []int{1, 3, 2}, []int{1, 1},
+			&ValidationError[int]{InvalidPeakIndex, 1, 0, -1, 0, -1, 0}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			p := CreatePeaksWith[int](test.samples, test.peaks)
+			err := Validate[int](&p)
+			if test.err == nil {
+				if err != nil {
+					t.Fatalf("expected no error, got %v", err)
+				}
+				return
+			}
+			var e *ValidationError[int]
+			if !errors.As(err, &e) {
+				t.Fatalf("expected %v, got %v", test.err, err)
+			}
+			if *e != *test.err {
+				t.Errorf("expected %+v, got %+v", *test.err, *e)
+			}
+			if !errors.Is(err, ErrInvalidPeaks) {
+				t.Errorf("expected %v to be %v", err, ErrInvalidPeaks)
+			}
+		})
+	}
+}
+
+func TestValidateSecondary(t *testing.T) {
+	samples := []int{1, 3, 2, 5, 1, 4, 2}
+	secondary := DetectPeaksInPrimary[int](DetectPeaks[int](samples))
+	if err := Validate[int](&secondary); err != nil {
+		t.Fatal(err)
+	}
+	// The level samples are 3, 5 and 4, of which only the 5 is a peak.
+	secondary.peaks = []int{0, 1}
+	err := Validate[int](&secondary)
+	if err == nil || err.Error() != "false peak at sample 0: value 3, left none, right 5 at sample 1" {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
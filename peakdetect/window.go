@@ -0,0 +1,375 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import (
+	"fmt"
+	"slices"
+)
+
+// WindowedDetector detects the peaks among the last N samples of a stream,
+// i.e., in a sliding window over the stream, which is kept in a ring buffer
+// of a fixed size. Its peaks are the same as those of DetectPeakPyramid, had
+// it been given the samples in the window, at every level of the hierarchy.
+//
+// Rather than detect the peaks again, every time a sample enters the window,
+// and another one leaves it, the detector keeps every level of the hierarchy,
+// as a run of nodes, each of which is a run of equal samples of the level,
+// such that the nodes next to each other differ. A node is a peak if it is
+// greater than the nodes next to it, which DetectPeaks decides by merging
+// each of them as a cluster of its own. Therefore, when a node enters, or
+// leaves, a level at one of its ends, only the node next to it may become,
+// or stop being, a peak, along with the entering node itself, and only the
+// node at the same end of the level above changes, in turn. Each sample then
+// takes a bounded amount of work at each level, however long the runs are.
+//
+// A WindowedDetector must not be copied after first use.
+type WindowedDetector[T Number] struct {
+	samples []T
+	// The index within the ring of the oldest sample in the window.
+	head  int
+	count int
+	// The index within the stream of the next sample to be pushed.
+	next int
+	// The nodes of each level, where levels[0] is the plateaus of the window,
+	// and the nodes of levels[k] are made up of the nodes of levels[k-1] that
+	// are peaks, i.e., of the peaks at level k. Every level, except levels[0],
+	// has some nodes, since the detection stops at the first level without
+	// peaks.
+	levels []*deque[*node[T]]
+}
+
+// A node of levels[0] is a plateau of the samples in the window, from 'start'
+// to 'end', exclusive, which are indices within the stream. A node of any
+// other level is made up of its 'members', i.e., of the equal nodes, next to
+// each other, among the peaks of the level below. A node is shared by the
+// level below, therefore, as the oldest samples leave the window, the start
+// of a plateau moves at all the levels at once.
+type node[T Number] struct {
+	value      T
+	start, end int
+	members    deque[*node[T]]
+	// Whether the node is a peak, given the nodes next to it, i.e., whether it
+	// is a member of a node of the level above.
+	peak bool
+}
+
+// CreateWindowedDetector returns nil if the size of the window is not
+// positive. Use CreateWindowedDetectorE to find out why.
+func CreateWindowedDetector[T Number](size int) *WindowedDetector[T] {
+	d, _ := CreateWindowedDetectorE[T](size)
+	return d
+}
+
+func CreateWindowedDetectorE[T Number](size int) (*WindowedDetector[T], error) {
+	if size < 1 {
+		return nil, fmt.Errorf("%w: window of %d samples", ErrInvalidSampleCount, size)
+	}
+	return &WindowedDetector[T]{
+		samples: make([]T, size),
+		levels:  []*deque[*node[T]]{{}},
+	}, nil
+}
+
+// Push adds the next sample of the stream to the window, and, if the window
+// is full, removes the oldest sample from it. A NaN is not added. Use PushE
+// to find out why.
+func (d *WindowedDetector[T]) Push(sample T) {
+	_ = d.PushE(sample)
+}
+
+// PushE rejects a NaN, which cannot be ordered, and therefore, would fail the
+// detection of the peaks in any window that contains it, leaving the window
+// as it was.
+func (d *WindowedDetector[T]) PushE(sample T) error {
+	// Only a NaN is not equal to itself.
+	if sample != sample {
+		return fmt.Errorf("%w: sample %d", ErrNaNSample, d.next)
+	}
+	if d.count == len(d.samples) {
+		d.evict()
+	}
+	d.samples[(d.head+d.count)%len(d.samples)] = sample
+	d.count++
+
+	plateaus := d.levels[0]
+	if plateaus.len() > 0 && plateaus.back().value == sample {
+		plateaus.back().end++
+	} else {
+		d.pushBack(0, &node[T]{value: sample, start: d.next, end: d.next + 1})
+		d.trim()
+	}
+	d.next++
+	return nil
+}
+
+// Removes the oldest sample from the window.
+func (d *WindowedDetector[T]) evict() {
+	d.head = (d.head + 1) % len(d.samples)
+	d.count--
+	first := d.levels[0].front()
+	if first.start++; first.start < first.end {
+		return
+	}
+	d.popFront(0)
+	d.trim()
+}
+
+// GetSampleCount returns the number of samples in the window.
+func (d *WindowedDetector[T]) GetSampleCount() int {
+	return d.count
+}
+
+// GetSamples returns a copy of the samples in the window, oldest first.
+func (d *WindowedDetector[T]) GetSamples() []T {
+	samples := make([]T, d.count)
+	for i := range samples {
+		samples[i] = d.samples[(d.head+i)%len(d.samples)]
+	}
+	return samples
+}
+
+// GetLevelCount returns the number of levels of the hierarchy, as that of the
+// PeakPyramid detected to completion in the window. The primary level is always
+// counted, even if it has no peaks.
+func (d *WindowedDetector[T]) GetLevelCount() int {
+	return max(len(d.levels)-1, 1)
+}
+
+// GetPeaks returns the indices of the peaks at the specified level within the
+// window, where 0 is the oldest sample. A level above GetLevelCount has no peaks.
+func (d *WindowedDetector[T]) GetPeaks(level int) []int {
+	peaks := make([]int, 0)
+	if level < 1 || level >= len(d.levels) {
+		return peaks
+	}
+	oldest := d.next - d.count
+	nodes := d.levels[level]
+	for i := 0; i < nodes.len(); i++ {
+		peaks = nodes.at(i).appendSamples(peaks, oldest)
+	}
+	return peaks
+}
+
+// Appends the indices of the samples of the node, relative to the oldest
+// sample in the window, by going down its members to the plateaus.
+func (n *node[T]) appendSamples(samples []int, oldest int) []int {
+	if n.members.len() == 0 {
+		for at := n.start; at < n.end; at++ {
+			samples = append(samples, at-oldest)
+		}
+		return samples
+	}
+	for i := 0; i < n.members.len(); i++ {
+		samples = n.members.at(i).appendSamples(samples, oldest)
+	}
+	return samples
+}
+
+// The nodes enter, and leave, a level at either end of it, and then only
+// the nodes at that end can change whether they are peaks, which the level
+// above then learns about at the same end of it.
+
+// Adds the node at the back of level k.
+func (d *WindowedDetector[T]) pushBack(k int, n *node[T]) {
+	nodes := d.levels[k]
+	nodes.pushBack(n)
+	if nodes.len() > 1 {
+		d.update(k, nodes.len()-2, true)
+	}
+	d.update(k, nodes.len()-1, true)
+}
+
+// Removes the node at the back of level k.
+func (d *WindowedDetector[T]) popBack(k int) {
+	nodes := d.levels[k]
+	if n := nodes.popBack(); n.peak {
+		n.peak = false
+		d.removeMember(k+1, true)
+	}
+	if nodes.len() > 0 {
+		d.update(k, nodes.len()-1, true)
+	}
+}
+
+// Adds the node at the front of level k.
+func (d *WindowedDetector[T]) pushFront(k int, n *node[T]) {
+	nodes := d.levels[k]
+	nodes.pushFront(n)
+	if nodes.len() > 1 {
+		d.update(k, 1, false)
+	}
+	d.update(k, 0, false)
+}
+
+// Removes the node at the front of level k.
+func (d *WindowedDetector[T]) popFront(k int) {
+	nodes := d.levels[k]
+	if n := nodes.popFront(); n.peak {
+		n.peak = false
+		d.removeMember(k+1, false)
+	}
+	if nodes.len() > 0 {
+		d.update(k, 0, false)
+	}
+}
+
+// Finds out whether the node at 'at', which is at, or next to, the back of
+// level k, or its front, is a peak, and if that has changed, adds it to the
+// level above, or removes it from there, at the same end.
+func (d *WindowedDetector[T]) update(k int, at int, back bool) {
+	n := d.levels[k].at(at)
+	peak := d.isPeak(k, at)
+	if peak == n.peak {
+		return
+	}
+	n.peak = peak
+	if peak {
+		d.addMember(k+1, n, back)
+	} else {
+		d.removeMember(k+1, back)
+	}
+}
+
+// A node is a peak if DetectPeaks, given the nodes next to it, and the node
+// itself, finds it to be one, which it does by merging them as clusters of
+// their own, the same way it merges the samples left over after the last
+// cluster. The nodes next to each other differ, therefore, each of them is a
+// plateau of its own.
+func (d *WindowedDetector[T]) isPeak(k int, at int) bool {
+	nodes := d.levels[k]
+	// The merge only fails if neither side has any samples.
+	peaks := peakDetectSample[T](nodes.at(at).value)
+	index := 0
+	if at > 0 {
+		peaks, _ = merge[T](peakDetectSample[T](nodes.at(at-1).value), peaks)
+		index = 1
+	}
+	if at+1 < nodes.len() {
+		peaks, _ = merge[T](peaks, peakDetectSample[T](nodes.at(at+1).value))
+	}
+	return slices.Contains(peaks.peaks, index)
+}
+
+// Adds the peak to level k, at the back, or the front, where it joins the
+// node at that end, if that node is equal to it, or else, enters the level
+// as a node of its own.
+func (d *WindowedDetector[T]) addMember(k int, m *node[T], back bool) {
+	if k == len(d.levels) {
+		d.levels = append(d.levels, &deque[*node[T]]{})
+	}
+	nodes := d.levels[k]
+	if back {
+		if nodes.len() > 0 && nodes.back().value == m.value {
+			nodes.back().members.pushBack(m)
+			return
+		}
+		n := &node[T]{value: m.value}
+		n.members.pushBack(m)
+		d.pushBack(k, n)
+		return
+	}
+	if nodes.len() > 0 && nodes.front().value == m.value {
+		nodes.front().members.pushFront(m)
+		return
+	}
+	n := &node[T]{value: m.value}
+	n.members.pushFront(m)
+	d.pushFront(k, n)
+}
+
+// Removes the peak at the back, or the front, of level k, whose node then
+// leaves the level, if the peak was the last of its members.
+func (d *WindowedDetector[T]) removeMember(k int, back bool) {
+	nodes := d.levels[k]
+	if back {
+		n := nodes.back()
+		if n.members.popBack(); n.members.len() == 0 {
+			d.popBack(k)
+		}
+		return
+	}
+	n := nodes.front()
+	if n.members.popFront(); n.members.len() == 0 {
+		d.popFront(k)
+	}
+}
+
+// Drops the levels above the first one without any nodes, which, other than
+// the plateaus of the window, is the level above the last one with peaks.
+func (d *WindowedDetector[T]) trim() {
+	for k := 1; k < len(d.levels); k++ {
+		if d.levels[k].len() == 0 {
+			d.levels = d.levels[:k]
+			return
+		}
+	}
+}
+
+// A double-ended queue, in a ring buffer that grows as needed.
+type deque[E any] struct {
+	items []E
+	head  int
+	count int
+}
+
+func (q *deque[E]) len() int {
+	return q.count
+}
+
+func (q *deque[E]) at(i int) E {
+	return q.items[(q.head+i)%len(q.items)]
+}
+
+func (q *deque[E]) front() E {
+	return q.at(0)
+}
+
+func (q *deque[E]) back() E {
+	return q.at(q.count - 1)
+}
+
+func (q *deque[E]) grow() {
+	if q.count < len(q.items) {
+		return
+	}
+	items := make([]E, max(2*len(q.items), 4))
+	for i := 0; i < q.count; i++ {
+		items[i] = q.at(i)
+	}
+	q.items = items
+	q.head = 0
+}
+
+func (q *deque[E]) pushBack(item E) {
+	q.grow()
+	q.items[(q.head+q.count)%len(q.items)] = item
+	q.count++
+}
+
+func (q *deque[E]) pushFront(item E) {
+	q.grow()
+	q.head = (q.head + len(q.items) - 1) % len(q.items)
+	q.items[q.head] = item
+	q.count++
+}
+
+func (q *deque[E]) popBack() E {
+	var zero E
+	q.count--
+	at := (q.head + q.count) % len(q.items)
+	item := q.items[at]
+	q.items[at] = zero
+	return item
+}
+
+func (q *deque[E]) popFront() E {
+	var zero E
+	item := q.items[q.head]
+	q.items[q.head] = zero
+	q.head = (q.head + 1) % len(q.items)
+	q.count--
+	return item
+}
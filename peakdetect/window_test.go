@@ -0,0 +1,172 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package peakdetect
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWindowedDetector(t *testing.T) {
+	d := CreateWindowedDetector[int](5)
+	tests := []struct {
+		sample    int
+		window    []int
+		primary   []int
+		secondary []int
+	}{
+		{1, []int{1}, []int{}, []int{}},
+		{3, []int{1, 3}, []int{1}, []int{}},
+		{2, []int{1, 3, 2}, []int{1}, []int{}},
+		{5, []int{1, 3, 2, 5}, []int{1, 3}, []int{3}},
+		{1, []int{1, 3, 2, 5, 1}, []int{1, 3}, []int{3}},
+		{4, []int{3, 2, 5, 1, 4}, []int{0, 2, 4}, []int{2}},
+		{4, []int{2, 5, 1, 4, 4}, []int{1, 3, 4}, []int{1}},
+		{2, []int{5, 1, 4, 4, 2}, []int{0, 2, 3}, []int{0}},
+		{6, []int{1, 4, 4, 2, 6}, []int{1, 2, 4}, []int{4}},
+		{6, []int{4, 4, 2, 6, 6}, []int{0, 1, 3, 4}, []int{3, 4}},
+		{6, []int{4, 2, 6, 6, 6}, []int{0, 2, 3, 4}, []int{2, 3, 4}},
+		{6, []int{2, 6, 6, 6, 6}, []int{1, 2, 3, 4}, []int{}},
+		{6, []int{6, 6, 6, 6, 6}, []int{}, []int{}},
+	}
+	for _, test := range tests {
+		d.Push(test.sample)
+		if !slices.Equal(test.window, d.GetSamples()) {
+			t.Fatalf("expected window %v, got %v", test.window, d.GetSamples())
+		}
+		if !slices.Equal(test.primary, d.GetPeaks(1)) {
+			t.Errorf("%v: expected primary peaks %v, got %v", test.window, test.primary, d.GetPeaks(1))
+		}
+		if !slices.Equal(test.secondary, d.GetPeaks(2)) {
+			t.Errorf("%v: expected secondary peaks %v, got %v", test.window, test.secondary, d.GetPeaks(2))
+		}
+	}
+}
+
+// At every step, the peaks at every level are the same as those detected in
+// the samples of the window, and therefore, so is the number of levels.
+func TestWindowedDetectorLevels(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, size := range []int{1, 2, 3, 4, 7, 16, 100} {
+		for _, values := range []int{2, 3, 10} {
+			d := CreateWindowedDetector[int](size)
+			for i := 0; i < 2000; i++ {
+				d.Push(r.Intn(values))
+				expectWindow(t, d)
+			}
+		}
+	}
+}
+
+func expectWindow[T Number](t *testing.T, d *WindowedDetector[T]) {
+	t.Helper()
+	window := d.GetSamples()
+	expected := DetectPeakPyramidToCompletion(window)
+	if d.GetLevelCount() != expected.GetLevelCount() {
+		t.Fatalf("%v: expected %d levels, got %d", window, expected.GetLevelCount(), d.GetLevelCount())
+	}
+	for level := 1; level <= expected.GetLevelCount()+1; level++ {
+		peaks := []int{}
+		if level <= expected.GetLevelCount() {
+			peaks = expected.GetPeaks(level)
+		}
+		if !slices.Equal(peaks, d.GetPeaks(level)) {
+			t.Fatalf("%v: level %d: expected %v, got %v", window, level, peaks, d.GetPeaks(level))
+		}
+	}
+	if primary := DetectPeaks(window); !slices.Equal(primary.GetPeaks(), d.GetPeaks(1)) {
+		t.Fatalf("%v: expected primary peaks %v, got %v", window, primary.GetPeaks(), d.GetPeaks(1))
+	}
+	for iterations := 2; iterations <= d.GetLevelCount(); iterations++ {
+		secondary, _ := IteratePeakDetect(uint(iterations), window)
+		if !slices.Equal(secondary.GetPrimaryPeaks(), d.GetPeaks(iterations)) {
+			t.Fatalf("%v: expected %v after %d iterations, got %v", window, secondary.GetPrimaryPeaks(), iterations, d.GetPeaks(iterations))
+		}
+	}
+}
+
+// Pushing a sample takes the same work however long the window is, on input
+// whose levels are long runs of equal plateaus, such as alternating samples,
+// as well as on flat input. The number of allocations per push is the same in
+// a small window as in a large one, and 200,000 samples through a window of
+// 100,000 take milliseconds, rather than the minutes they would, were each
+// push to take time in proportion to the length of the window.
+func TestWindowedDetectorWork(t *testing.T) {
+	inputs := []struct {
+		name   string
+		sample func(i int) int
+	}{
+		{"alternating", func(i int) int { return 3 * (1 - i%2) }},
+		{"flat", func(i int) int { return 1 }},
+	}
+	for _, input := range inputs {
+		allocs := func(size int) float64 {
+			d := CreateWindowedDetector[int](size)
+			i := 0
+			for ; i < 2*size; i++ {
+				d.Push(input.sample(i))
+			}
+			return testing.AllocsPerRun(1000, func() {
+				d.Push(input.sample(i))
+				i++
+			})
+		}
+		if small, large := allocs(1_000), allocs(100_000); small != large {
+			t.Errorf("%s: expected the same allocations per push, got %v in a small window, %v in a large one", input.name, small, large)
+		}
+
+		d := CreateWindowedDetector[int](100_000)
+		start := time.Now()
+		for i := 0; i < 200_000; i++ {
+			d.Push(input.sample(i))
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("%s: expected the pushes to take constant time, took %v", input.name, elapsed)
+		}
+		expectWindow(t, d)
+	}
+}
+
+func TestWindowedDetectorNaN(t *testing.T) {
+	d := CreateWindowedDetector[float64](3)
+	d.Push(1)
+	d.Push(2)
+	if err := d.PushE(math.NaN()); !errors.Is(err, ErrNaNSample) {
+		t.Errorf("expected %v, got %v", ErrNaNSample, err)
+	}
+	d.Push(1)
+	if expected := []float64{1, 2, 1}; !slices.Equal(expected, d.GetSamples()) {
+		t.Errorf("expected window %v, got %v", expected, d.GetSamples())
+	}
+	if expected := []int{1}; !slices.Equal(expected, d.GetPeaks(1)) {
+		t.Errorf("expected peaks %v, got %v", expected, d.GetPeaks(1))
+	}
+}
+
+func TestCreateWindowedDetector(t *testing.T) {
+	if _, err := CreateWindowedDetectorE[int](0); !errors.Is(err, ErrInvalidSampleCount) {
+		t.Errorf("expected %v, got %v", ErrInvalidSampleCount, err)
+	}
+	if d := CreateWindowedDetector[int](-1); d != nil {
+		t.Error("expected no detector")
+	}
+}
+
+func FuzzWindowedDetector(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add(uint8(5), seed)
+	}
+	f.Fuzz(func(t *testing.T, size uint8, data []byte) {
+		d := CreateWindowedDetector[int](int(size%32) + 1)
+		for _, sample := range fuzzInts(data) {
+			d.Push(sample)
+			expectWindow(t, d)
+		}
+	})
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package prometheus detects the peaks in the series returned by a range
+// query against the Prometheus HTTP API, i.e., /api/v1/query_range.
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andr31g/peak-detector/peakdetect"
+)
+
+// ErrUnexpectedResult is reported when the query does not result in a
+// matrix, i.e., in a set of series, which is all a range query results in.
+var ErrUnexpectedResult = errors.New("unexpected result")
+
+// APIError is an error reported by Prometheus.
+type APIError struct {
+	StatusCode int
+	Type       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("prometheus: %s (%d): %s", e.Type, e.StatusCode, e.Message)
+}
+
+// Range is the time range, and the resolution, of a range query.
+type Range struct {
+	Start time.Time
+	End   time.Time
+	Step  time.Duration
+}
+
+// Series is one of the series returned by a range query, identified by
+// its labels. The samples are kept as they are, including those whose value
+// is NaN, e.g., of a division by zero, which cannot be ordered, and therefore
+// must be treated as a peakdetect.NaNPolicy specifies.
+type Series struct {
+	Labels  map[string]string
+	Samples peakdetect.Series[float64]
+}
+
+// Result is the peaks detected in one of the series returned by a range query.
+type Result struct {
+	Labels map[string]string
+	Peaks  peakdetect.SeriesPeaks[float64]
+}
+
+// The most that is read of a response, beyond which it is taken to be truncated.
+const maxResponseBytes = 256 << 20
+
+type Client struct {
+	endpoint string
+	client   *http.Client
+}
+
+// CreateClient creates a client of the Prometheus server at the specified
+// address, e.g., http://localhost:9090. If the HTTP client is nil, then
+// http.DefaultClient is used.
+func CreateClient(address string, client *http.Client) (*Client, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("prometheus: unsupported address %q", address)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Client{strings.TrimSuffix(u.String(), "/") + "/api/v1/query_range", client}, nil
+}
+
+// QueryRange evaluates the PromQL expression over the range, and returns
+// each of the resulting series.
+func (c *Client) QueryRange(ctx context.Context, query string, r Range) ([]Series, error) {
+	form := url.Values{
+		"query": {query},
+		"start": {formatTime(r.Start)},
+		"end":   {formatTime(r.End)},
+		"step":  {strconv.FormatFloat(r.Step.Seconds(), 'f', -1, 64)},
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	response, err := c.client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	var body struct {
+		Status    string `json:"status"`
+		ErrorType string `json:"errorType"`
+		Error     string `json:"error"`
+		Data      struct {
+			ResultType string `json:"resultType"`
+			Result     []struct {
+				Metric map[string]string `json:"metric"`
+				Values []samplePair      `json:"values"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(io.LimitReader(response.Body, maxResponseBytes)).Decode(&body); err != nil {
+		if response.StatusCode != http.StatusOK {
+			return nil, &APIError{response.StatusCode, http.StatusText(response.StatusCode), err.Error()}
+		}
+		return nil, fmt.Errorf("prometheus: %w", err)
+	}
+	if body.Status != "success" {
+		return nil, &APIError{response.StatusCode, body.ErrorType, body.Error}
+	}
+	if body.Data.ResultType != "matrix" {
+		return nil, fmt.Errorf("prometheus: %w: %q", ErrUnexpectedResult, body.Data.ResultType)
+	}
+
+	series := make([]Series, len(body.Data.Result))
+	for i, result := range body.Data.Result {
+		timestamps := make([]time.Time, len(result.Values))
+		values := make([]float64, len(result.Values))
+		for j, pair := range result.Values {
+			timestamps[j], values[j] = pair.timestamp, pair.value
+		}
+		series[i] = Series{result.Metric, peakdetect.CreateSeries[float64](timestamps, values)}
+	}
+	return series, nil
+}
+
+// IteratePeakDetect evaluates the PromQL expression over the range, and
+// detects the peaks in each of the resulting series, the same way
+// peakdetect.IteratePeakDetectSeries does, skipping the NaN samples, i.e.,
+// with peakdetect.NaNSkip.
+func (c *Client) IteratePeakDetect(ctx context.Context, iterations uint, query string, r Range) ([]Result, error) {
+	series, err := c.QueryRange(ctx, query, r)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]Result, len(series))
+	for i, s := range series {
+		peaks, _, err := peakdetect.IteratePeakDetectSeriesWithNaNPolicy[float64](iterations, s.Samples, peakdetect.NaNSkip)
+		if err != nil {
+			return nil, fmt.Errorf("series %v: %w", s.Labels, err)
+		}
+		results[i] = Result{s.Labels, peaks}
+	}
+	return results, nil
+}
+
+// Prometheus encodes each sample as a pair of the timestamp, in seconds
+// since the epoch, and the value, as a string, e.g., [1700000000.5, "1.25"].
+type samplePair struct {
+	timestamp time.Time
+	value     float64
+}
+
+func (p *samplePair) UnmarshalJSON(b []byte) error {
+	var pair [2]json.RawMessage
+	if err := json.Unmarshal(b, &pair); err != nil {
+		return err
+	}
+	var seconds float64
+	if err := json.Unmarshal(pair[0], &seconds); err != nil {
+		return fmt.Errorf("sample timestamp: %w", err)
+	}
+	var value string
+	if err := json.Unmarshal(pair[1], &value); err != nil {
+		return fmt.Errorf("sample value: %w", err)
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("sample value: %w", err)
+	}
+	p.timestamp = time.UnixMilli(int64(math.Round(seconds * 1000))).UTC()
+	p.value = v
+	return nil
+}
+
+func formatTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.UnixMilli())/1000, 'f', -1, 64)
+}
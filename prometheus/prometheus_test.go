@@ -0,0 +1,173 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+const matrix = `{
+	"status": "success",
+	"data": {
+		"resultType": "matrix",
+		"result": [
+			{
+				"metric": {"__name__": "up", "instance": "a"},
+				"values": [[1700000000, "1"], [1700000015, "3"], [1700000030, "NaN"], [1700000045, "2"], [1700000060, "5"], [1700000075, "1"]]
+			},
+			{
+				"metric": {"__name__": "up", "instance": "b"},
+				"values": [[1700000000.5, "2"], [1700000015.5, "2"]]
+			}
+		]
+	}
+}`
+
+var (
+	start = time.Unix(1700000000, 0).UTC()
+	end   = start.Add(75 * time.Second)
+)
+
+// Serves the canned response, after checking that the
+// request is a range query of the expected expression.
+func prometheus(t *testing.T, status int, body string) *Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query_range" {
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		for name, expected := range map[string]string{"query": "up", "start": "1700000000", "end": "1700000075", "step": "15"} {
+			if actual := r.PostForm.Get(name); actual != expected {
+				t.Errorf("expected %s %q, got %q", name, expected, actual)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	client, err := CreateClient(server.URL+"/", server.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestQueryRange(t *testing.T) {
+	series, err := prometheus(t, http.StatusOK, matrix).QueryRange(context.Background(), "up", Range{start, end, 15 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 2 {
+		t.Fatalf("expected 2 series, got %d", len(series))
+	}
+	if expected := map[string]string{"__name__": "up", "instance": "a"}; !reflect.DeepEqual(expected, series[0].Labels) {
+		t.Errorf("expected labels %v, got %v", expected, series[0].Labels)
+	}
+	// The NaN is kept, such that each sample is at its own index.
+	values := series[0].Samples.Values
+	if expected := []float64{1, 3, 2, 5, 1}; len(values) != 6 || !math.IsNaN(values[2]) ||
+		!slices.Equal(expected, slices.Delete(slices.Clone(values), 2, 3)) {
+		t.Errorf("expected values %v, with a NaN at 2, got %v", expected, values)
+	}
+	expected := []time.Time{start, start.Add(15 * time.Second), start.Add(30 * time.Second), start.Add(45 * time.Second), start.Add(60 * time.Second), end}
+	if !slices.Equal(expected, series[0].Samples.Timestamps) {
+		t.Errorf("expected timestamps %v, got %v", expected, series[0].Samples.Timestamps)
+	}
+	if timestamp := series[1].Samples.Timestamps[0]; !timestamp.Equal(start.Add(500 * time.Millisecond)) {
+		t.Errorf("expected the timestamp to be %v, got %v", start.Add(500*time.Millisecond), timestamp)
+	}
+}
+
+func TestIteratePeakDetect(t *testing.T) {
+	results, err := prometheus(t, http.StatusOK, matrix).IteratePeakDetect(context.Background(), 1, "up", Range{start, end, 15 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	peaks := results[0].Peaks
+	if results[0].Labels["instance"] != "a" || peaks.Level != 2 {
+		t.Errorf("expected the secondary peaks of instance a, got level %d of %v", peaks.Level, results[0].Labels)
+	}
+	// The NaN is skipped, and the peak is at its index among all the samples.
+	if !slices.Equal(peaks.Indices, []int{4}) || !slices.Equal(peaks.Values, []float64{5}) ||
+		!slices.Equal(peaks.Timestamps, []time.Time{start.Add(60 * time.Second)}) {
+		t.Errorf("expected the peak of 5 at %v, got %+v", start.Add(60*time.Second), peaks)
+	}
+	if len(results[1].Peaks.Indices) != 0 {
+		t.Errorf("expected no peaks in instance b, got %+v", results[1].Peaks)
+	}
+}
+
+func TestQueryRangeErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+		check  func(error) bool
+	}{
+		{
+			name:   "bad data",
+			status: http.StatusBadRequest,
+			body:   `{"status": "error", "errorType": "bad_data", "error": "parse error"}`,
+			check: func(err error) bool {
+				var e *APIError
+				return errors.As(err, &e) && e.StatusCode == http.StatusBadRequest && e.Type == "bad_data" && e.Message == "parse error"
+			},
+		},
+		{
+			name:   "unavailable",
+			status: http.StatusServiceUnavailable,
+			body:   `unavailable`,
+			check: func(err error) bool {
+				var e *APIError
+				return errors.As(err, &e) && e.StatusCode == http.StatusServiceUnavailable
+			},
+		},
+		{
+			name:   "vector",
+			status: http.StatusOK,
+			body:   `{"status": "success", "data": {"resultType": "vector", "result": []}}`,
+			check: func(err error) bool {
+				return errors.Is(err, ErrUnexpectedResult)
+			},
+		},
+		{
+			name:   "malformed value",
+			status: http.StatusOK,
+			body:   `{"status": "success", "data": {"resultType": "matrix", "result": [{"metric": {}, "values": [[1, "one"]]}]}}`,
+			check: func(err error) bool {
+				return err != nil
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := prometheus(t, test.status, test.body).QueryRange(context.Background(), "up", Range{start, end, 15 * time.Second})
+			if !test.check(err) {
+				t.Errorf("unexpected error %v", err)
+			}
+		})
+	}
+}
+
+func TestCreateClient(t *testing.T) {
+	if _, err := CreateClient("localhost:9090", nil); err == nil {
+		t.Error("expected an address without a scheme to be rejected")
+	}
+}
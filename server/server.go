@@ -0,0 +1,209 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+// Package server exposes the peak detector over HTTP, such that it can be
+// called from services that are not written in Go.
+//
+// POST /v1/detect takes a JSON object with the samples, optionally along
+// with their timestamps, and the number of levels to detect:
+//
+//	{"samples": [1, 3, 2], "timestamps": ["2024-01-01T00:00:00Z", ...], "levels": 2}
+//
+// and responds with the peaks at each level, starting with the primary peaks
+// at level 1. The indices are those of the samples, irrespective of the level:
+//
+//	{"levels": [{"level": 1, "peaks": [{"index": 1, "value": 3, "timestamp": ...}]}]}
+//
+// Errors are reported as a JSON object with an "error" member, along with
+// the appropriate status code.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/andr31g/peak-detector/peakdetect"
+)
+
+const DefaultMaxBytes = 8 << 20
+
+// DetectRequest is the body of a request to detect peaks. Levels is the
+// number of levels to detect, counting the primary level, such that 1, or
+// none at all, detects only the primary peaks. If the detection is to run
+// to completion, the number of levels is ignored.
+type DetectRequest struct {
+	Samples    []float64   `json:"samples"`
+	Timestamps []time.Time `json:"timestamps,omitempty"`
+	Levels     uint        `json:"levels,omitempty"`
+	Complete   bool        `json:"complete,omitempty"`
+}
+
+type DetectResponse struct {
+	Levels []Level `json:"levels"`
+}
+
+// Level is the peaks at one level of the hierarchy. The detection stops
+// at the first level without peaks, which, when the detection runs to
+// completion, is left out, unless it is the primary level.
+type Level struct {
+	Level int    `json:"level"`
+	Peaks []Peak `json:"peaks"`
+}
+
+type Peak struct {
+	Index     int        `json:"index"`
+	Value     float64    `json:"value"`
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type Server struct {
+	maxBytes int64
+	mux      *http.ServeMux
+}
+
+// CreateServer creates a server that accepts request bodies of up to the
+// specified number of bytes, or DefaultMaxBytes, if it is not positive.
+func CreateServer(maxBytes int64) *Server {
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	s := &Server{maxBytes, http.NewServeMux()}
+	s.mux.HandleFunc("/v1/detect", s.detect)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) detect(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	var request DetectRequest
+	if status, err := decode(w, r, s.maxBytes, &request); err != nil {
+		writeError(w, status, err)
+		return
+	}
+	if request.Timestamps != nil && len(request.Timestamps) != len(request.Samples) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("%w: %d timestamps for %d samples",
+			peakdetect.ErrInvalidSampleCount, len(request.Timestamps), len(request.Samples)))
+		return
+	}
+	levels, err := detectLevels(request)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, DetectResponse{levels})
+}
+
+// Decodes the body of the request, which must be a single JSON value, and
+// returns the status code to respond with if that is not the case.
+func decode(w http.ResponseWriter, r *http.Request, maxBytes int64, v any) (int, error) {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBytes))
+	decoder.DisallowUnknownFields()
+	err := decoder.Decode(v)
+	if err == nil {
+		if _, err = decoder.Token(); err == io.EOF {
+			return http.StatusOK, nil
+		} else if err == nil {
+			err = errors.New("unexpected data after the request")
+		}
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge, fmt.Errorf("request exceeds %d bytes", tooLarge.Limit)
+	} else if err == io.EOF {
+		return http.StatusBadRequest, errors.New("empty request")
+	}
+	return http.StatusBadRequest, err
+}
+
+// Detects the primary peaks, and then the peaks at each level above them,
+// until either the number of levels is reached, or a level has no peaks.
+func detectLevels(request DetectRequest) ([]Level, error) {
+	levels := max(request.Levels, 1)
+	var pyramid peakdetect.PeakPyramid[float64]
+	var err error
+	if request.Complete {
+		pyramid, err = peakdetect.DetectPeakPyramidToCompletionE[float64](request.Samples)
+	} else {
+		pyramid, err = peakdetect.DetectPeakPyramidE[float64](levels, request.Samples)
+	}
+	if err != nil {
+		return nil, err
+	}
+	described := make([]Level, 0, pyramid.GetLevelCount()+1)
+	for l := 1; l <= pyramid.GetLevelCount(); l++ {
+		described = append(described, level(l, request, pyramid.GetPeaks(l), pyramid.GetValues(l)))
+	}
+	// The pyramid stops short of the levels only at a level without peaks,
+	// which is reported, unless the primary level already has none.
+	if !request.Complete && uint(len(described)) < levels && len(described[len(described)-1].Peaks) > 0 {
+		described = append(described, Level{len(described) + 1, []Peak{}})
+	}
+	return described, nil
+}
+
+func level(number int, request DetectRequest, indices []int, values []float64) Level {
+	peaks := make([]Peak, len(indices))
+	for i, at := range indices {
+		peaks[i] = Peak{Index: at, Value: values[i]}
+		if request.Timestamps != nil {
+			peaks[i].Timestamp = &request.Timestamps[at]
+		}
+	}
+	return Level{number, peaks}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, ErrorResponse{err.Error()})
+}
+
+// Serve serves the handler on the listener until the context is done, and
+// then shuts down gracefully, i.e., it stops accepting new connections, and
+// waits up to the grace period for the requests in progress to complete.
+func Serve(ctx context.Context, l net.Listener, handler http.Handler, grace time.Duration) error {
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.Serve(l)
+	}()
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	shutdown, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	if err := srv.Shutdown(shutdown); err != nil {
+		return err
+	}
+	if err := <-errs; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2024 Andrei Gill. All rights reserved.
+// Use of this source code is governed by the Apache License, Version 2.0
+// that can be found in the LICENSE file.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDetect(t *testing.T) {
+	t0 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Minute)
+	t3 := t0.Add(3 * time.Minute)
+	tests := []struct {
+		name     string
+		body     string
+		expected DetectResponse
+	}{
+		{
+			name:     "primary",
+			body:     `{"samples": [1, 3, 2, 5, 1]}`,
+			expected: DetectResponse{[]Level{{1, []Peak{{Index: 1, Value: 3}, {Index: 3, Value: 5}}}}},
+		},
+		{
+			name: "levels",
+			body: `{"samples": [1, 3, 2, 5, 1], "levels": 3}`,
+			expected: DetectResponse{[]Level{
+				{1, []Peak{{Index: 1, Value: 3}, {Index: 3, Value: 5}}},
+				{2, []Peak{{Index: 3, Value: 5}}},
+				{3, []Peak{}},
+			}},
+		},
+		{
+			name: "complete",
+			body: `{"samples": [1, 3, 2, 5, 1], "complete": true}`,
+			expected: DetectResponse{[]Level{
+				{1, []Peak{{Index: 1, Value: 3}, {Index: 3, Value: 5}}},
+				{2, []Peak{{Index: 3, Value: 5}}},
+			}},
+		},
+		{
+			name: "timestamps",
+			body: `{"samples": [1, 3, 2, 5, 1], "levels": 2, "timestamps": [
+				"2024-01-01T00:00:00Z", "2024-01-01T00:01:00Z", "2024-01-01T00:02:00Z",
+				"2024-01-01T00:03:00Z", "2024-01-01T00:04:00Z"]}`,
+			expected: DetectResponse{[]Level{
+				{1, []Peak{{1, 3, &t1}, {3, 5, &t3}}},
+				{2, []Peak{{3, 5, &t3}}},
+			}},
+		},
+		{
+			name:     "no samples",
+			body:     `{"samples": []}`,
+			expected: DetectResponse{[]Level{{1, []Peak{}}}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			response := post(t, CreateServer(0), test.body)
+			if response.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d, %s", http.StatusOK, response.Code, response.Body)
+			}
+			var actual DetectResponse
+			if err := json.Unmarshal(response.Body.Bytes(), &actual); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(test.expected, actual) {
+				t.Errorf("expected %+v, got %+v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestDetectErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		body   string
+		status int
+	}{
+		{"method", http.MethodGet, "", http.StatusMethodNotAllowed},
+		{"empty", http.MethodPost, "", http.StatusBadRequest},
+		{"malformed", http.MethodPost, `{"samples": [1, 2`, http.StatusBadRequest},
+		{"unknown field", http.MethodPost, `{"values": [1, 2]}`, http.StatusBadRequest},
+		{"iterations", http.MethodPost, `{"samples": [1, 2], "iterations": 2}`, http.StatusBadRequest},
+		{"trailing data", http.MethodPost, `{"samples": [1]} {}`, http.StatusBadRequest},
+		{"timestamps", http.MethodPost, `{"samples": [1, 2], "timestamps": ["2024-01-01T00:00:00Z"]}`, http.StatusBadRequest},
+		{"too large", http.MethodPost, `{"samples": [` + strings.Repeat("1, ", 100) + `1]}`, http.StatusRequestEntityTooLarge},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest(test.method, "/v1/detect", strings.NewReader(test.body))
+			response := httptest.NewRecorder()
+			CreateServer(128).ServeHTTP(response, request)
+			if response.Code != test.status {
+				t.Errorf("expected status %d, got %d", test.status, response.Code)
+			}
+			if contentType := response.Header().Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("expected a JSON response, got %q", contentType)
+			}
+			var e ErrorResponse
+			if err := json.Unmarshal(response.Body.Bytes(), &e); err != nil || e.Error == "" {
+				t.Errorf("expected an error, got %s", response.Body)
+			}
+		})
+	}
+}
+
+func TestServe(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(ctx, l, CreateServer(0), time.Second)
+	}()
+
+	response, err := http.Post("http://"+l.Addr().String()+"/v1/detect", "application/json", strings.NewReader(`{"samples": [1, 2, 1]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("expected status %d, got %d", http.StatusOK, response.StatusCode)
+	}
+
+	cancel()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("expected a graceful shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the server did not shut down")
+	}
+}
+
+func post(t *testing.T, handler http.Handler, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	request := httptest.NewRequest(http.MethodPost, "/v1/detect", strings.NewReader(body))
+	response := httptest.NewRecorder()
+	handler.ServeHTTP(response, request)
+	return response
+}